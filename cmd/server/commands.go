@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"URL_Shortener_Ruckus_Networks/internals/auth"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+const usage = `usage:
+  server                                  run the HTTP server
+  server export [-o file] [-tenant id]    write every link as JSON Lines
+  server import [-policy p] [-dry-run] [-tenant id] [file]
+                                          replay an export (policy skip, overwrite or fail)
+  server keygen -id name [-scopes s,...] [-tenant id]
+                                          print a new API key and its record for API_KEYS_FILE
+
+export and import use the backend configured by STORAGE_BACKEND and friends,
+for the default tenant unless -tenant is given.`
+
+// runCommand runs a CLI subcommand and returns the process exit code
+func runCommand(name string, args []string) int {
+	switch name {
+	case "export":
+		return runExport(args)
+	case "import":
+		return runImport(args)
+	case "keygen":
+		return runKeygen(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", name, usage)
+	return 2
+}
+
+// runExport - server export [-o file] [-tenant id]
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "write to this file instead of stdout")
+	tenantID := flags.String("tenant", "", "export this tenant's links instead of the default tenant's")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if err := checkPersistentStorage(); err != nil {
+		log.Printf("export: %v", err)
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Printf("export: %v", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	store := openStorage(*tenantID, newLogger())
+	defer store.Close()
+
+	written, err := storage.Export(context.Background(), store, w)
+	if err != nil {
+		log.Printf("export: failed after links=%d: %v", written, err)
+		return 1
+	}
+	log.Printf("export: links=%d", written)
+	return 0
+}
+
+// runImport - server import [-policy skip|overwrite|fail] [-dry-run] [-tenant id] [file]
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	policyFlag := flags.String("policy", string(storage.ConflictSkip), "what to do with codes stored with different contents: skip, overwrite or fail")
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	tenantID := flags.String("tenant", "", "import into this tenant instead of the default tenant")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	policy, err := storage.ParseConflictPolicy(*policyFlag)
+	if err != nil {
+		log.Printf("import: %v", err)
+		return 2
+	}
+	if err := checkPersistentStorage(); err != nil {
+		log.Printf("import: %v", err)
+		return 2
+	}
+
+	var r io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Printf("import: %v", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+
+	store := openStorage(*tenantID, newLogger())
+	defer store.Close()
+
+	report, err := storage.Import(context.Background(), store, r, storage.ImportOptions{Policy: policy, DryRun: *dryRun})
+
+	// the report goes to stdout even when the import is refused
+	fmt.Printf("records=%d created=%d unchanged=%d conflicted=%d skipped=%d overwritten=%d dry_run=%v\n",
+		report.Records, report.Created, report.Unchanged, report.Conflicted, report.Skipped, report.Overwritten, report.DryRun)
+	for _, c := range report.Conflicts {
+		fmt.Printf("conflict line=%d code=%s existing=%s incoming=%s\n", c.Line, c.Code, c.Existing, c.Incoming)
+	}
+
+	if err != nil {
+		log.Printf("import: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// checkPersistentStorage rejects the memory backend for export and import:
+// the command would open a store of its own, empty and gone once it exits,
+// instead of the running server's
+func checkPersistentStorage() error {
+	switch os.Getenv("STORAGE_BACKEND") {
+	case "", "memory":
+		return errors.New("the memory backend keeps links only inside the server process; set STORAGE_BACKEND to file, sqlite or redis, or use /api/admin/export and /api/admin/import")
+	}
+	return nil
+}
+
+// runKeygen - server keygen -id name [-scopes links:create,links:read]
+//
+// Prints the raw key, which is not stored anywhere, then the record to add
+// to the API_KEYS_FILE array.
+func runKeygen(args []string) int {
+	flags := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	id := flags.String("id", "", "name of the key's owner, shown in logs")
+	scopeList := flags.String("scopes", string(auth.ScopeCreate), "comma-separated scopes: links:create, links:read, links:admin")
+	tenantID := flags.String("tenant", "", "tenant whose links the key manages, default tenant if unset")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *id == "" {
+		log.Printf("keygen: -id is required")
+		return 2
+	}
+
+	var scopes []auth.Scope
+	for _, s := range strings.Split(*scopeList, ",") {
+		scope, err := auth.ParseScope(strings.TrimSpace(s))
+		if err != nil {
+			log.Printf("keygen: %v", err)
+			return 2
+		}
+		scopes = append(scopes, scope)
+	}
+
+	raw, hash, err := auth.GenerateKey()
+	if err != nil {
+		log.Printf("keygen: %v", err)
+		return 1
+	}
+	record, _ := json.Marshal(auth.Key{ID: *id, Hash: hash, Scopes: scopes, Tenant: *tenantID})
+
+	fmt.Println(raw)
+	fmt.Println(string(record))
+	return 0
+}
@@ -1,18 +1,56 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"URL_Shortener_Ruckus_Networks/internals/analytics"
+	"URL_Shortener_Ruckus_Networks/internals/auth"
 	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/logging"
+	"URL_Shortener_Ruckus_Networks/internals/metrics"
+	"URL_Shortener_Ruckus_Networks/internals/ratelimit"
 	"URL_Shortener_Ruckus_Networks/internals/service"
 	"URL_Shortener_Ruckus_Networks/internals/storage"
+	"URL_Shortener_Ruckus_Networks/internals/tenant"
 
 	"github.com/gorilla/mux"
 )
 
+const (
+	// how often expired links are reclaimed from storage
+	janitorInterval = time.Minute
+
+	// click events buffered between redirects and the analytics aggregator
+	clickBufferSize = 4096
+
+	// database file created in STORAGE_PATH by the sqlite backend
+	sqliteFileName = "links.db"
+)
+
 func main() {
+	// export / import subcommands run against the configured backend and exit
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+	os.Exit(runServer())
+}
+
+// runServer serves the API until SIGINT or SIGTERM and returns the exit code
+func runServer() int {
 	// Env
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -24,24 +62,493 @@ func main() {
 		baseURL = "http://localhost:8080"
 	}
 
-	// storage
-	store := storage.NewMemoryStorage()
+	// logging
+	logger := newLogger()
 
-	// service
-	svc := service.NewURLService(store, baseURL)
+	// storage, cache and service of the default tenant
+	def := openTenant("", baseURL, logger)
+	stacks := []*tenantStack{def}
+
+	// extra short domains the default tenant publishes links on
+	var shortDomains []string
+	if v := os.Getenv("SHORT_DOMAINS"); v != "" {
+		for _, d := range strings.Split(v, ",") {
+			shortDomains = append(shortDomains, tenant.NormalizeHost(strings.TrimSpace(d)))
+		}
+		if err := def.service.SetDomains(shortDomains); err != nil {
+			log.Fatalf("invalid SHORT_DOMAINS %q", v)
+		}
+	}
+
+	// analytics
+	clicks := analytics.NewRecorder(analytics.NewAggregator(), clickBufferSize, logger)
 
 	// handler
-	h := handler.NewHandler(svc)
+	h := handler.NewHandler(def.service, clicks, logger)
+	if v := os.Getenv("MAX_BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Fatalf("invalid MAX_BATCH_SIZE %q", v)
+		}
+		h.SetMaxBatchSize(n)
+	}
+	if v := os.Getenv("MAX_IMPORT_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			log.Fatalf("invalid MAX_IMPORT_BYTES %q", v)
+		}
+		h.SetMaxImportBytes(n)
+	}
+	if v := os.Getenv("REDIRECT_STATUS"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil || !storage.ValidRedirectStatus(status) {
+			log.Fatalf("invalid REDIRECT_STATUS %q", v)
+		}
+		h.SetRedirectStatus(status)
+	}
+	h.SetPermanentMaxAge(envDuration("REDIRECT_CACHE_MAX_AGE", handler.DefaultPermanentMaxAge))
+
+	// further tenants, each with its own storage and base URL
+	if path := os.Getenv("TENANTS_FILE"); path != "" {
+		tenants, err := tenant.LoadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var baseHost string
+		if u, err := url.Parse(baseURL); err == nil {
+			baseHost = tenant.NormalizeHost(u.Host)
+		}
+		for _, t := range tenants {
+			for _, host := range t.AllHosts() {
+				if host == baseHost {
+					log.Fatalf("BASE_URL host %s belongs to tenant %s", host, t.ID)
+				}
+				if slices.Contains(shortDomains, host) {
+					log.Fatalf("SHORT_DOMAINS host %s belongs to tenant %s", host, t.ID)
+				}
+			}
+			stack := openTenant(t.ID, t.BaseURL, logger.With("tenant", t.ID))
+			if err := stack.service.SetDomains(t.Hosts); err != nil {
+				log.Fatal(err)
+			}
+			if err := h.AddTenant(t.ID, t.AllHosts(), stack.service); err != nil {
+				log.Fatal(err)
+			}
+			stacks = append(stacks, stack)
+			logger.Info("Tenant added", "tenant", t.ID, "base_url", t.BaseURL, "hosts", t.AllHosts())
+		}
+	}
+
+	// API keys
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		keys, err := auth.LoadKeyFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		h.SetKeyStore(keys)
+		logger.Info("API key authentication enabled", "keys", keys.Len())
+	} else {
+		logger.Warn("API_KEYS_FILE is not set, the /api routes are open to anyone")
+	}
+
+	// rate limits, per API key or client IP
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		proxies, err := parsePrefixes(v)
+		if err != nil {
+			log.Fatalf("invalid TRUSTED_PROXIES %q: %v", v, err)
+		}
+		h.SetTrustedProxies(proxies)
+	}
+	shortenLimit := newLimiter("shorten", "RATE_LIMIT_SHORTEN")
+	redirectLimit := newLimiter("redirect", "RATE_LIMIT_REDIRECT")
+
+	// metrics
+	m := newMetrics(stacks, clicks)
+	h.SetMetrics(m)
 
 	// Routers
 	r := mux.NewRouter()
-	r.HandleFunc("/api/shorten", h.ShortenURL).Methods("POST")
-	r.HandleFunc("/{shortCode}", h.RedirectURL).Methods("GET", "HEAD")
+	r.Use(m.Middleware)
+	if d := envDuration("REQUEST_TIMEOUT", 0); d > 0 {
+		r.Use(handler.Timeout(d))
+	}
+
+	// API routes need a key with the route's scope once API_KEYS_FILE is set
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(h.Authenticate)
+	api.HandleFunc("/shorten", h.Limit(shortenLimit, h.Require(auth.ScopeCreate, h.ShortenURL))).Methods("POST")
+	api.HandleFunc("/shorten/batch", h.Limit(shortenLimit, h.Require(auth.ScopeCreate, h.ShortenBatch))).Methods("POST")
+	api.HandleFunc("/links", h.Require(auth.ScopeRead, h.ListLinks)).Methods("GET")
+	api.HandleFunc("/links/{shortCode}", h.Require(auth.ScopeRead, h.GetLink)).Methods("GET")
+	api.HandleFunc("/links/{shortCode}", h.Require(auth.ScopeAdmin, h.UpdateLink)).Methods("PATCH")
+	api.HandleFunc("/links/{shortCode}", h.Require(auth.ScopeAdmin, h.DeleteLink)).Methods("DELETE")
+	api.HandleFunc("/links/{shortCode}/stats", h.Require(auth.ScopeRead, h.LinkStats)).Methods("GET")
+	api.HandleFunc("/admin/export", h.Require(auth.ScopeAdmin, h.ExportLinks)).Methods("GET")
+	api.HandleFunc("/admin/import", h.Require(auth.ScopeAdmin, h.ImportLinks)).Methods("POST")
+
+	// probes, metrics and redirects stay public
+	r.Handle("/metrics", m).Methods("GET")
+	r.HandleFunc("/healthz", h.Healthz).Methods("GET")
+	r.HandleFunc("/readyz", h.Readyz).Methods("GET")
+	r.HandleFunc("/{shortCode}", h.Limit(redirectLimit, h.RedirectURL)).Methods("GET", "HEAD")
 
 	// Start server
-	log.Printf("Server starting on port %s", port)
-	log.Printf("Base URL: %s", baseURL)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	srv := &http.Server{
+		Addr:         ":" + port,
+		Handler:      r,
+		ReadTimeout:  envDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout: envDuration("HTTP_WRITE_TIMEOUT", 60*time.Second),
+		IdleTimeout:  envDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	logger.Info("Server starting", "port", port, "base_url", baseURL)
+
+	code := 0
+	err := serve(srv, h, logger, envDuration("SHUTDOWN_DELAY", 0), envDuration("SHUTDOWN_TIMEOUT", 30*time.Second))
+	if err != nil {
+		logger.Error("Server stopped", "err", err)
+		code = 1
+	}
+
+	// flush buffered clicks, then whatever the backends have not yet written
+	clicks.Close()
+	for _, stack := range stacks {
+		if err := stack.backend.Close(); err != nil {
+			logger.Error("Storage close failed", "tenant", stack.id, "err", err)
+			code = 1
+		}
+	}
+	logger.Info("Server exited")
+	return code
+}
+
+// serve runs srv until SIGINT or SIGTERM, then drains it: /readyz starts
+// failing, after delay the listener closes, and in-flight requests get up to
+// timeout to finish before their connections are cut
+func serve(srv *http.Server, h *handler.Handler, logger *slog.Logger, delay, timeout time.Duration) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-stop:
+		logger.Info("Server draining", "signal", sig.String(), "delay", delay, "timeout", timeout)
+	}
+
+	h.Drain()
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
+		return err
+	}
+	return nil
+}
+
+// newMetrics registers the request metrics plus gauges read from every
+// tenant's backend and cache, and from the click recorder, on every scrape
+func newMetrics(stacks []*tenantStack, clicks *analytics.Recorder) *metrics.Server {
+	m := metrics.NewServer()
+
+	// only the in-memory backends know their size without a query
+	var sized []interface{ Len() int }
+	var caches []*storage.CachedStorage
+	for _, stack := range stacks {
+		if s, ok := stack.backend.(interface{ Len() int }); ok {
+			sized = append(sized, s)
+		}
+		if stack.cache != nil {
+			caches = append(caches, stack.cache)
+		}
+	}
+
+	if len(sized) > 0 {
+		m.NewGaugeFunc("urlshortener_storage_links", "Links held in memory by every tenant, expired links not yet purged included.",
+			func() float64 {
+				var n int
+				for _, s := range sized {
+					n += s.Len()
+				}
+				return float64(n)
+			})
+	}
+
+	if len(caches) > 0 {
+		cacheStats := func() storage.CacheStats {
+			var total storage.CacheStats
+			for _, c := range caches {
+				stats := c.Stats()
+				total.Hits += stats.Hits
+				total.Misses += stats.Misses
+				total.Evictions += stats.Evictions
+				total.Entries += stats.Entries
+			}
+			return total
+		}
+		m.NewCounterFunc("urlshortener_cache_hits_total", "Redirect lookups answered by the cache.",
+			func() float64 { return float64(cacheStats().Hits) })
+		m.NewCounterFunc("urlshortener_cache_misses_total", "Redirect lookups that went to storage.",
+			func() float64 { return float64(cacheStats().Misses) })
+		m.NewCounterFunc("urlshortener_cache_evictions_total", "Cache entries evicted to stay within CACHE_SIZE.",
+			func() float64 { return float64(cacheStats().Evictions) })
+		m.NewGaugeFunc("urlshortener_cache_entries", "Entries currently cached.",
+			func() float64 { return float64(cacheStats().Entries) })
+	}
+
+	m.NewCounterFunc("urlshortener_clicks_dropped_total", "Click events dropped because the analytics buffer was full.",
+		func() float64 { return float64(clicks.Dropped()) })
+	return m
+}
+
+// tenantStack is the storage, cache and service serving one tenant
+type tenantStack struct {
+	id      string // empty for the default tenant
+	backend storageBackend
+	cache   *storage.CachedStorage // nil without CACHE_SIZE
+	service *service.URLService
+}
+
+// openTenant opens tenant id's storage, with the cache and code generator
+// configured by env, and the service shortening to baseURL
+func openTenant(id, baseURL string, logger *slog.Logger) *tenantStack {
+	stack := &tenantStack{id: id}
+
+	// storage
+	stack.backend = openStorage(id, logger)
+	stack.backend.StartJanitor(janitorInterval)
+	var store storage.Storage = stack.backend
+
+	// read-through cache in front of the backend
+	if v := os.Getenv("CACHE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatalf("invalid CACHE_SIZE %q", v)
+		}
+		if n > 0 {
+			opts := storage.DefaultCacheOptions()
+			opts.Size = n
+			opts.TTL = envDuration("CACHE_TTL", opts.TTL)
+			opts.NegativeTTL = envDuration("CACHE_NEGATIVE_TTL", opts.NegativeTTL)
+			stack.cache = storage.NewCachedStorage(store, opts)
+			store = stack.cache
+		}
+	}
+
+	// short code generation
+	codeLength := service.DefaultCodeLength
+	if v := os.Getenv("CODE_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid CODE_LENGTH %q", v)
+		}
+		codeLength = n
+	}
+	generator, err := service.NewCodeGenerator(os.Getenv("CODE_STRATEGY"), codeLength)
+	if err != nil {
 		log.Fatal(err)
 	}
+	if counter, ok := generator.(*service.CounterGenerator); ok {
+		if err := service.SeedCounter(context.Background(), counter, store); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// service
+	stack.service = service.NewURLService(store, baseURL, generator, logger)
+	stack.service.SetNormalizeOptions(service.NormalizeOptions{
+		Enabled:       envBool("URL_NORMALIZE", true),
+		SortQuery:     envBool("URL_SORT_QUERY", false),
+		StripTracking: envBool("URL_STRIP_TRACKING", false),
+	})
+	return stack
+}
+
+// storageBackend is a storage backend as opened by openStorage
+type storageBackend interface {
+	storage.Storage
+	StartJanitor(interval time.Duration)
+	Close() error
+}
+
+// newLogger builds the logger selected by LOG_FORMAT and LOG_LEVEL and makes
+// it the slog default
+func newLogger() *slog.Logger {
+	opts, err := logging.ParseOptions(os.Getenv("LOG_FORMAT"), os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := logging.New(os.Stderr, opts)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// openStorage opens tenant's backend selected by STORAGE_BACKEND. The
+// default tenant, "", uses STORAGE_PATH and REDIS_PREFIX as they are; other
+// tenants get tenants/<id> under STORAGE_PATH and tenant:<id>: after the
+// Redis prefix, so no two tenants share a code or URL index.
+func openStorage(tenantID string, logger *slog.Logger) storageBackend {
+	switch os.Getenv("STORAGE_BACKEND") {
+	case "", "memory":
+		if v := os.Getenv("MEMORY_SHARDS"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				log.Fatalf("invalid MEMORY_SHARDS %q", v)
+			}
+			return storage.NewShardedMemoryStorage(n, logger)
+		}
+		return storage.NewMemoryStorage(logger)
+	case "file":
+		path := storagePath(tenantID)
+
+		opts := storage.DefaultFileOptions()
+		opts.Logger = logger
+		policy, err := storage.ParseSyncPolicy(os.Getenv("STORAGE_FSYNC"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Sync = policy
+
+		fileStore, err := storage.NewFileStorage(path, opts)
+		if errors.Is(err, storage.ErrLocked) {
+			log.Fatalf("%v: stop the server using STORAGE_PATH first, or use /api/admin/export and /api/admin/import", err)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return fileStore
+	case "sqlite":
+		path := storagePath(tenantID)
+
+		sqliteStore, err := storage.NewSQLiteStorage(filepath.Join(path, sqliteFileName), logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sqliteStore
+	case "redis":
+		opts := storage.DefaultRedisOptions()
+		opts.Logger = logger
+		if v := os.Getenv("REDIS_ADDR"); v != "" {
+			opts.Addr = v
+		}
+		opts.Password = os.Getenv("REDIS_PASSWORD")
+		if v := os.Getenv("REDIS_DB"); v != "" {
+			db, err := strconv.Atoi(v)
+			if err != nil || db < 0 {
+				log.Fatalf("invalid REDIS_DB %q", v)
+			}
+			opts.DB = db
+		}
+		if v, ok := os.LookupEnv("REDIS_PREFIX"); ok {
+			opts.Prefix = v
+		}
+		if tenantID != "" {
+			opts.Prefix += "tenant:" + tenantID + ":"
+		}
+
+		redisStore, err := storage.NewRedisStorage(opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return redisStore
+	}
+
+	log.Fatalf("unknown STORAGE_BACKEND %q", os.Getenv("STORAGE_BACKEND"))
+	return nil
+}
+
+// storagePath - directory of tenantID's file or sqlite backend
+func storagePath(tenantID string) string {
+	path := os.Getenv("STORAGE_PATH")
+	if path == "" {
+		path = "data"
+	}
+	if tenantID != "" {
+		path = filepath.Join(path, "tenants", tenantID)
+	}
+	return path
+}
+
+// envBool reads a boolean env var, falling back to def when unset
+func envBool(name string, def bool) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("invalid %s %q", name, v)
+	}
+	return b
+}
+
+// newLimiter builds the named rate limit from env, e.g. RATE_LIMIT_SHORTEN=30/m
+// with RATE_LIMIT_SHORTEN_BURST, nil when the rate is unset
+func newLimiter(name, env string) *ratelimit.Limiter {
+	v := os.Getenv(env)
+	if v == "" {
+		return nil
+	}
+	requests, per, err := ratelimit.ParseRate(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", env, err)
+	}
+	opts := ratelimit.Options{Name: name, Requests: requests, Per: per}
+	if b := os.Getenv(env + "_BURST"); b != "" {
+		if opts.Burst, err = strconv.Atoi(b); err != nil || opts.Burst < 1 {
+			log.Fatalf("invalid %s_BURST %q", env, b)
+		}
+	}
+	if c := os.Getenv("RATE_LIMIT_CLIENTS"); c != "" {
+		if opts.MaxClients, err = strconv.Atoi(c); err != nil || opts.MaxClients < 1 {
+			log.Fatalf("invalid RATE_LIMIT_CLIENTS %q", c)
+		}
+	}
+
+	limiter, err := ratelimit.New(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return limiter
+}
+
+// parsePrefixes reads a comma-separated list of CIDR ranges or single IPs
+func parsePrefixes(v string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// envDuration reads a Go duration env var such as "5s", falling back to def when unset
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Fatalf("invalid %s %q", name, v)
+	}
+	return d
 }
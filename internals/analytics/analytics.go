@@ -0,0 +1,227 @@
+package analytics
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/logging"
+)
+
+// Event is a single redirect served for a short code
+type Event struct {
+	Timestamp time.Time
+	Tenant    string // empty for the default tenant
+	ShortCode string
+	Referrer  string
+	UserAgent string
+	ClientIP  string
+	Method    string // GET or HEAD
+}
+
+// dropLogInterval spaces out the full-buffer warning, so a burst of drops
+// costs one log record rather than one per redirect
+const dropLogInterval = 10 * time.Second
+
+// Recorder hands click events to an Aggregator on a background goroutine so
+// recording never blocks the redirect path. Events arriving while the buffer
+// is full are dropped and counted; events arriving after Close are ignored.
+type Recorder struct {
+	events      chan Event
+	agg         *Aggregator
+	dropped     atomic.Uint64
+	lastDropLog atomic.Int64 // unix nanoseconds of the last drop warning
+	logger      *slog.Logger
+
+	mu        sync.RWMutex // held for reading while sending, so Close waits for senders
+	closed    bool
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewRecorder creates a recorder feeding agg through a buffer of bufferSize
+// events. A nil logger uses slog.Default().
+func NewRecorder(agg *Aggregator, bufferSize int, logger *slog.Logger) *Recorder {
+	r := &Recorder{
+		events: make(chan Event, bufferSize),
+		agg:    agg,
+		logger: logging.OrDefault(logger).With("component", "analytics"),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record queues an event without blocking, false if it had to be dropped or
+// the recorder is closed
+func (r *Recorder) Record(e Event) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return false
+	}
+	select {
+	case r.events <- e:
+		return true
+	default:
+		r.logDrop(r.dropped.Add(1))
+		return false
+	}
+}
+
+// logDrop warns about a full buffer at most once per dropLogInterval, with
+// the running total of dropped events
+func (r *Recorder) logDrop(dropped uint64) {
+	now := time.Now().UnixNano()
+	last := r.lastDropLog.Load()
+	if now-last < int64(dropLogInterval) || !r.lastDropLog.CompareAndSwap(last, now) {
+		return
+	}
+	r.logger.Warn("Record buffer full, dropping events", "dropped_total", dropped)
+}
+
+// Dropped returns how many events were discarded because the buffer was full
+func (r *Recorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Stats returns the aggregated counts for shortCode of the default tenant
+func (r *Recorder) Stats(shortCode string) Stats {
+	return r.agg.Stats(shortCode)
+}
+
+// TenantStats returns the aggregated counts for shortCode of tenant
+func (r *Recorder) TenantStats(tenant, shortCode string) Stats {
+	return r.agg.TenantStats(tenant, shortCode)
+}
+
+// Close stops accepting events and waits until the buffer is drained.
+// Handlers still running may call Record afterwards; their events are ignored.
+func (r *Recorder) Close() {
+	r.closeOnce.Do(func() {
+		r.mu.Lock()
+		r.closed = true
+		r.mu.Unlock()
+
+		close(r.stop)
+		<-r.done
+	})
+}
+
+// run drains the event channel into the aggregator. The channel is never
+// closed, as a late Record would panic sending on it; once stop is closed no
+// more events arrive, so what is buffered then is drained and run returns.
+func (r *Recorder) run() {
+	defer close(r.done)
+	for {
+		select {
+		case e := <-r.events:
+			r.agg.Add(e)
+		case <-r.stop:
+			for {
+				select {
+				case e := <-r.events:
+					r.agg.Add(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// DayCount is the number of clicks on a single UTC day
+type DayCount struct {
+	Date   string `json:"date"` // YYYY-MM-DD
+	Clicks int64  `json:"clicks"`
+}
+
+// Stats is the aggregated view of a short code's clicks
+type Stats struct {
+	Total int64      `json:"total"`
+	Get   int64      `json:"get"`
+	Head  int64      `json:"head"`
+	Daily []DayCount `json:"daily"`
+}
+
+// counts is the per-code state kept by the Aggregator
+type counts struct {
+	total int64
+	get   int64
+	head  int64
+	daily map[string]int64
+}
+
+// linkKey - tenants have their own code spaces, so codes are counted per tenant
+type linkKey struct {
+	tenant    string
+	shortCode string
+}
+
+// Aggregator keeps total and per-day click counts in memory
+type Aggregator struct {
+	mu     sync.RWMutex
+	byCode map[linkKey]*counts
+}
+
+// NewAggregator creates an empty aggregator
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		byCode: make(map[linkKey]*counts),
+	}
+}
+
+// Add folds a single event into the counts
+func (a *Aggregator) Add(e Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := linkKey{e.Tenant, e.ShortCode}
+	c, exists := a.byCode[key]
+	if !exists {
+		c = &counts{daily: make(map[string]int64)}
+		a.byCode[key] = c
+	}
+
+	c.total++
+	if e.Method == "HEAD" {
+		c.head++
+	} else {
+		c.get++
+	}
+	c.daily[e.Timestamp.UTC().Format(time.DateOnly)]++
+}
+
+// Stats returns the counts for shortCode of the default tenant, days in ascending order
+func (a *Aggregator) Stats(shortCode string) Stats {
+	return a.TenantStats("", shortCode)
+}
+
+// TenantStats returns the counts for shortCode of tenant, days in ascending order
+func (a *Aggregator) TenantStats(tenant, shortCode string) Stats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	stats := Stats{Daily: []DayCount{}}
+	c, exists := a.byCode[linkKey{tenant, shortCode}]
+	if !exists {
+		return stats
+	}
+
+	stats.Total = c.total
+	stats.Get = c.get
+	stats.Head = c.head
+	for date, clicks := range c.daily {
+		stats.Daily = append(stats.Daily, DayCount{Date: date, Clicks: clicks})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date < stats.Daily[j].Date
+	})
+
+	return stats
+}
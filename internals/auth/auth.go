@@ -0,0 +1,188 @@
+// Package auth holds API keys and the scopes they grant. Keys are only
+// ever stored as SHA-256 hashes; the raw key is shown once, when generated.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrInvalidKey  = errors.New("invalid api key record")
+)
+
+// Scope is a permission granted to a key
+type Scope string
+
+const (
+	ScopeCreate Scope = "links:create" // shorten URLs
+	ScopeRead   Scope = "links:read"   // list links and read their metadata and stats
+	ScopeAdmin  Scope = "links:admin"  // everything, including retarget, delete, export and import
+)
+
+// ParseScope converts a flag or key file value into a Scope
+func ParseScope(s string) (Scope, error) {
+	switch scope := Scope(s); scope {
+	case ScopeCreate, ScopeRead, ScopeAdmin:
+		return scope, nil
+	}
+	return "", fmt.Errorf("unknown scope %q", s)
+}
+
+// prefix of generated keys, so a leaked key is recognisable in logs and scanners
+const keyPrefix = "us_"
+
+// Key is a stored API key
+type Key struct {
+	ID     string  `json:"id"`   // names the key's owner in logs
+	Hash   string  `json:"hash"` // HashKey of the raw key
+	Scopes []Scope `json:"scopes"`
+	Tenant string  `json:"tenant,omitempty"` // tenant whose links the key manages, empty for the default tenant
+}
+
+// Allows reports whether the key grants scope. links:admin grants every scope.
+func (k Key) Allows(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// HashKey returns the hex SHA-256 of a raw key. Keys are 256 random bits,
+// so a fast hash is enough to make a leaked key file useless.
+func HashKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateKey returns a new random raw key and its hash
+func GenerateKey() (raw, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return raw, HashKey(raw), nil
+}
+
+// KeyStore looks keys up by the hash of the raw key presented
+type KeyStore interface {
+	Lookup(ctx context.Context, hash string) (Key, error)
+}
+
+// MemoryKeyStore implements KeyStore with an in-memory map
+type MemoryKeyStore struct {
+	mu     sync.RWMutex
+	byHash map[string]Key
+}
+
+// NewMemoryKeyStore creates a key store holding keys
+func NewMemoryKeyStore(keys ...Key) (*MemoryKeyStore, error) {
+	s := &MemoryKeyStore{byHash: make(map[string]Key)}
+	for _, key := range keys {
+		if err := s.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add stores key, rejecting a malformed record or a duplicate id or hash
+func (s *MemoryKeyStore) Add(key Key) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	key.Hash = strings.ToLower(key.Hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, dup := s.byHash[key.Hash]; dup {
+		return fmt.Errorf("%w: key %s: hash already in use", ErrInvalidKey, key.ID)
+	}
+	for _, existing := range s.byHash {
+		if existing.ID == key.ID {
+			return fmt.Errorf("%w: key %s: id already in use", ErrInvalidKey, key.ID)
+		}
+	}
+	s.byHash[key.Hash] = key
+	return nil
+}
+
+// Lookup finds the key whose raw form hashes to hash
+func (s *MemoryKeyStore) Lookup(ctx context.Context, hash string) (Key, error) {
+	if err := ctx.Err(); err != nil {
+		return Key{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.byHash[hash]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Len returns how many keys are stored
+func (s *MemoryKeyStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.byHash)
+}
+
+// LoadKeyFile reads a JSON array of Key records, as printed by the keygen
+// command, into a MemoryKeyStore
+func LoadKeyFile(path string) (*MemoryKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read key file: %w", err)
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidKey, path, err)
+	}
+	return NewMemoryKeyStore(keys...)
+}
+
+// validateKey checks a key record before it is stored
+func validateKey(key Key) error {
+	if key.ID == "" {
+		return fmt.Errorf("%w: id is required", ErrInvalidKey)
+	}
+	if b, err := hex.DecodeString(key.Hash); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("%w: key %s: hash must be 64 hex characters", ErrInvalidKey, key.ID)
+	}
+	if len(key.Scopes) == 0 {
+		return fmt.Errorf("%w: key %s: at least one scope is required", ErrInvalidKey, key.ID)
+	}
+	for _, scope := range key.Scopes {
+		if _, err := ParseScope(string(scope)); err != nil {
+			return fmt.Errorf("%w: key %s: %v", ErrInvalidKey, key.ID, err)
+		}
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// WithKey returns a copy of ctx carrying the authenticated key
+func WithKey(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// KeyFromContext returns the key authenticated for the request, if any
+func KeyFromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
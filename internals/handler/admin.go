@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+// DefaultMaxImportBytes caps the body of ImportLinks unless
+// SetMaxImportBytes is called; the whole import is held in memory
+const DefaultMaxImportBytes = 64 << 20
+
+// error codes specific to the admin API
+const (
+	CodeInvalidRecord   = "invalid_record"
+	CodeImportConflict  = "import_conflict"
+	CodeImportTooLarge  = "import_too_large"
+	CodeInvalidArgument = "invalid_argument"
+)
+
+// ImportConflictResponse handler - a record whose code is stored with a different URL
+type ImportConflictResponse struct {
+	Line     int    `json:"line"`
+	Code     string `json:"code"`
+	Existing string `json:"existing_url"`
+	Incoming string `json:"incoming_url"`
+}
+
+// ImportResponse handler - the import report
+type ImportResponse struct {
+	DryRun      bool                     `json:"dry_run"`
+	Records     int                      `json:"records"`
+	Created     int                      `json:"created"`
+	Unchanged   int                      `json:"unchanged"`
+	Conflicted  int                      `json:"conflicted"`
+	Skipped     int                      `json:"skipped"`
+	Overwritten int                      `json:"overwritten"`
+	Conflicts   []ImportConflictResponse `json:"conflicts"`
+	Error       string                   `json:"error,omitempty"`
+	Code        string                   `json:"code,omitempty"`
+}
+
+// ExportLinks API - GET /api/admin/export
+//
+// Streams every link as JSON Lines, one storage.ExportRecord per line. A large
+// export may take longer than HTTP_WRITE_TIMEOUT, so it is not held to it.
+func (h *Handler) ExportLinks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="links.jsonl"`)
+	streamResponse(w, false)
+
+	written, err := h.tenantFor(r).service.ExportLinks(r.Context(), w)
+	if err != nil {
+		if written > 0 {
+			// the status line is out, so the short body is the only signal
+			h.logger.Warn("ExportLinks truncated", "links", written, "err", err)
+			return
+		}
+		w.Header().Del("Content-Disposition")
+		if h.sendContextError(w, "ExportLinks", err) {
+			return
+		}
+		h.logger.Error("ExportLinks internal error", "err", err)
+		h.sendError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("ExportLinks exported", "links", written)
+}
+
+// ImportLinks API - POST /api/admin/import?policy=skip|overwrite|fail&dry_run=true
+//
+// Replays an export from the request body. Conflicts under policy=fail
+// answer 409 with the report and write nothing.
+func (h *Handler) ImportLinks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	policy, err := storage.ParseConflictPolicy(query.Get("policy"))
+	if err != nil {
+		h.logger.Debug("ImportLinks invalid policy", "policy", query.Get("policy"))
+		h.sendErrorCode(w, "policy must be skip, overwrite or fail", CodeInvalidArgument, http.StatusBadRequest)
+		return
+	}
+	opts := storage.ImportOptions{Policy: policy}
+	if v := query.Get("dry_run"); v != "" {
+		if opts.DryRun, err = strconv.ParseBool(v); err != nil {
+			h.logger.Debug("ImportLinks invalid dry_run", "dry_run", v)
+			h.sendErrorCode(w, "dry_run must be true or false", CodeInvalidArgument, http.StatusBadRequest)
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, h.maxImport)
+	report, err := h.tenantFor(r).service.ImportLinks(r.Context(), body, opts)
+	response := importResponse(report)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		h.logger.Info("ImportLinks imported", "records", report.Records, "created", report.Created, "unchanged", report.Unchanged,
+			"skipped", report.Skipped, "overwritten", report.Overwritten, "dry_run", report.DryRun)
+		h.sendJSON(w, response, http.StatusOK)
+	case errors.As(err, &tooLarge):
+		h.logger.Debug("ImportLinks body too large", "max", tooLarge.Limit)
+		h.sendErrorCode(w, fmt.Sprintf("Import exceeds the maximum of %d bytes", tooLarge.Limit), CodeImportTooLarge, http.StatusRequestEntityTooLarge)
+	case errors.Is(err, storage.ErrInvalidRecord):
+		h.logger.Debug("ImportLinks invalid record", "err", err)
+		h.sendErrorCode(w, err.Error(), CodeInvalidRecord, http.StatusBadRequest)
+	case err == storage.ErrImportConflict:
+		h.logger.Warn("ImportLinks aborted", "conflicts", report.Conflicted)
+		response.Error = "Import conflicts with stored links, nothing was written"
+		response.Code = CodeImportConflict
+		h.sendJSON(w, response, http.StatusConflict)
+	default:
+		if h.sendContextError(w, "ImportLinks", err) {
+			return
+		}
+		h.logger.Error("ImportLinks internal error", "err", err)
+		h.sendError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// importResponse - API view of an import report
+func importResponse(report storage.ImportReport) ImportResponse {
+	resp := ImportResponse{
+		DryRun:      report.DryRun,
+		Records:     report.Records,
+		Created:     report.Created,
+		Unchanged:   report.Unchanged,
+		Conflicted:  report.Conflicted,
+		Skipped:     report.Skipped,
+		Overwritten: report.Overwritten,
+		Conflicts:   make([]ImportConflictResponse, 0, len(report.Conflicts)),
+	}
+	for _, c := range report.Conflicts {
+		resp.Conflicts = append(resp.Conflicts, ImportConflictResponse{Line: c.Line, Code: c.Code, Existing: c.Existing, Incoming: c.Incoming})
+	}
+	return resp
+}
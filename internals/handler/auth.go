@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"URL_Shortener_Ruckus_Networks/internals/auth"
+)
+
+// error codes for rejected credentials
+const (
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+)
+
+// SetKeyStore turns on API key authentication against keys, nil disables it
+func (h *Handler) SetKeyStore(keys auth.KeyStore) {
+	h.keys = keys
+}
+
+// Authenticate is a middleware for the /api routes. It looks up the key in
+// the Authorization: Bearer or X-API-Key header and answers 401 when it is
+// missing or unknown. Without a key store every request passes.
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.keys == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		raw := presentedKey(r)
+		if raw == "" {
+			h.logger.Debug("Authenticate missing key", "path", r.URL.Path)
+			h.sendUnauthorized(w, "API key required")
+			return
+		}
+
+		key, err := h.keys.Lookup(r.Context(), auth.HashKey(raw))
+		if err != nil {
+			if h.sendContextError(w, "Authenticate", err) {
+				return
+			}
+			if err != auth.ErrKeyNotFound {
+				h.logger.Error("Authenticate key lookup failed", "err", err)
+				h.sendError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			h.logger.Warn("Authenticate unknown key", "path", r.URL.Path, "client_ip", h.clientIP(r))
+			h.sendUnauthorized(w, "Invalid API key")
+			return
+		}
+
+		if !h.knownTenant(key.Tenant) {
+			h.logger.Warn("Authenticate key of unknown tenant", "key_id", key.ID, "tenant", key.Tenant)
+			h.sendErrorCode(w, "API key's tenant is not configured", CodeForbidden, http.StatusForbidden)
+			return
+		}
+
+		h.logger.Debug("Authenticate", "key_id", key.ID, "tenant", key.Tenant, "path", r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(auth.WithKey(r.Context(), key)))
+	})
+}
+
+// Require wraps next so it only runs for a key granting scope, answering
+// 403 otherwise. It relies on Authenticate having run first.
+func (h *Handler) Require(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.keys == nil {
+			next(w, r)
+			return
+		}
+
+		key, ok := auth.KeyFromContext(r.Context())
+		if !ok {
+			h.sendUnauthorized(w, "API key required")
+			return
+		}
+		if !key.Allows(scope) {
+			h.logger.Info("Require scope missing", "key_id", key.ID, "scope", scope, "path", r.URL.Path)
+			h.sendErrorCode(w, "API key lacks scope "+string(scope), CodeForbidden, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// sendUnauthorized - 401 with the challenge clients expect
+func (h *Handler) sendUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	h.sendErrorCode(w, message, CodeUnauthorized, http.StatusUnauthorized)
+}
+
+// presentedKey - raw key from Authorization: Bearer, else X-API-Key
+func presentedKey(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if scheme, token, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
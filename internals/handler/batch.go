@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"URL_Shortener_Ruckus_Networks/internals/metrics"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+)
+
+// DefaultMaxBatchSize is the batch limit used unless SetMaxBatchSize is called
+const DefaultMaxBatchSize = 1000
+
+// longest NDJSON line accepted in a streamed batch
+const maxNDJSONLine = 64 * 1024
+
+// error codes specific to the batch API
+const (
+	CodeBatchTooLarge = "batch_too_large"
+	CodeInvalidItem   = "invalid_item"
+)
+
+// BatchResult handler - outcome of one batch item, in input order
+type BatchResult struct {
+	Index     int    `json:"index"`
+	ShortURL  string `json:"short_url,omitempty"`
+	LongURL   string `json:"long_url,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// BatchResponse handler
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// ShortenBatch API - POST /api/shorten/batch
+//
+// Accepts a JSON array of ShortenRequest and answers with a BatchResponse, or
+// with Content-Type application/x-ndjson one request per line, answered by
+// one BatchResult per line as each item completes.
+func (h *Handler) ShortenBatch(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-ndjson" {
+		h.shortenBatchNDJSON(w, r)
+		return
+	}
+
+	// the array is held in memory, so its items get no more room than NDJSON
+	// lines; the extra line covers brackets, commas and whitespace
+	limit := int64(h.maxBatchSize+1) * maxNDJSONLine
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		if h.batchBodyTooLarge(w, err) {
+			return
+		}
+		h.logger.Debug("ShortenBatch body is not a JSON array")
+		h.sendError(w, "Request body must be a JSON array", http.StatusBadRequest)
+		return
+	}
+
+	var items []ShortenRequest
+	for dec.More() {
+		if len(items) == h.maxBatchSize {
+			h.logger.Debug("ShortenBatch batch too large", "max", h.maxBatchSize)
+			h.sendErrorCode(w, fmt.Sprintf("Batch exceeds the maximum of %d items", h.maxBatchSize), CodeBatchTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		var item ShortenRequest
+		if err := dec.Decode(&item); err != nil {
+			if h.batchBodyTooLarge(w, err) {
+				return
+			}
+			h.logger.Debug("ShortenBatch invalid item", "index", len(items), "err", err)
+			h.sendError(w, fmt.Sprintf("Invalid item at index %d", len(items)), http.StatusBadRequest)
+			return
+		}
+		items = append(items, item)
+	}
+	if _, err := dec.Token(); err != nil {
+		if h.batchBodyTooLarge(w, err) {
+			return
+		}
+		h.logger.Debug("ShortenBatch unterminated array", "err", err)
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// the request paid for the first item, the rest cost a token each
+	if d := h.charge(w, r, len(items)-1); !d.Allowed {
+		if len(items) > d.Limit {
+			h.sendErrorCode(w, fmt.Sprintf("Batch exceeds the rate limit burst of %d items", d.Limit), CodeBatchTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(d.RetryAfter))))
+		h.sendErrorCode(w, "Rate limit exceeded", CodeRateLimited, http.StatusTooManyRequests)
+		return
+	}
+
+	ctx, svc := r.Context(), h.tenantFor(r).service
+	response := BatchResponse{Results: make([]BatchResult, 0, len(items))}
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			h.sendContextError(w, "ShortenBatch", err)
+			return
+		}
+		response.Results = append(response.Results, h.batchItem(ctx, svc, i, item))
+	}
+
+	h.logger.Debug("ShortenBatch processed", "items", len(items))
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+// batchBodyTooLarge answers 413 and returns true if err is a JSON array body
+// running over its byte limit
+func (h *Handler) batchBodyTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	h.logger.Debug("ShortenBatch body too large", "max", tooLarge.Limit)
+	h.sendErrorCode(w, fmt.Sprintf("Batch exceeds the maximum of %d bytes", tooLarge.Limit), CodeBatchTooLarge, http.StatusRequestEntityTooLarge)
+	return true
+}
+
+// shortenBatchNDJSON streams results back as each line is processed, reading
+// the body while it writes and without HTTP_WRITE_TIMEOUT cutting it short
+func (h *Handler) shortenBatchNDJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	streamResponse(w, true)
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	emit := func(result BatchResult) {
+		enc.Encode(result)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxNDJSONLine)
+
+	ctx, svc := r.Context(), h.tenantFor(r).service
+	index := 0
+	for scanner.Scan() {
+		// the status line is out, so a timeout can only be reported in-band
+		if err := ctx.Err(); err != nil {
+			h.logger.Debug("ShortenBatch stream ended", "items", index, "err", err)
+			if reqErr := contextError(err); reqErr.status != statusClientClosedRequest {
+				emit(BatchResult{Index: index, Error: reqErr.message, Code: reqErr.code})
+			}
+			return
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if index == h.maxBatchSize {
+			h.logger.Debug("ShortenBatch stream too large", "max", h.maxBatchSize)
+			emit(BatchResult{Index: index, Error: fmt.Sprintf("Batch exceeds the maximum of %d items", h.maxBatchSize), Code: CodeBatchTooLarge})
+			return
+		}
+		if index > 0 && !h.charge(w, r, 1).Allowed {
+			emit(BatchResult{Index: index, Error: "Rate limit exceeded", Code: CodeRateLimited})
+			return
+		}
+
+		var item ShortenRequest
+		if err := json.Unmarshal(line, &item); err != nil {
+			h.logger.Debug("ShortenBatch invalid line", "index", index, "err", err)
+			h.countShorten(metrics.OutcomeInvalid)
+			emit(BatchResult{Index: index, Error: "Invalid JSON", Code: CodeInvalidItem})
+		} else {
+			emit(h.batchItem(ctx, svc, index, item))
+		}
+		index++
+	}
+
+	if err := scanner.Err(); err != nil {
+		h.logger.Warn("ShortenBatch stream aborted", "items", index, "err", err)
+		emit(BatchResult{Index: index, Error: "Invalid request body", Code: CodeInvalidItem})
+		return
+	}
+
+	h.logger.Debug("ShortenBatch streamed", "items", index)
+}
+
+// batchItem shortens one item and folds any error into its result
+func (h *Handler) batchItem(ctx context.Context, svc *service.URLService, index int, item ShortenRequest) BatchResult {
+	response, reqErr := h.shorten(ctx, svc, item)
+	if reqErr != nil {
+		return BatchResult{Index: index, LongURL: item.URL, Error: reqErr.message, Code: reqErr.code}
+	}
+	return BatchResult{
+		Index:     index,
+		ShortURL:  response.ShortURL,
+		LongURL:   response.LongURL,
+		ExpiresAt: response.ExpiresAt,
+	}
+}
@@ -1,10 +1,24 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"URL_Shortener_Ruckus_Networks/internals/analytics"
+	"URL_Shortener_Ruckus_Networks/internals/auth"
+	"URL_Shortener_Ruckus_Networks/internals/logging"
+	"URL_Shortener_Ruckus_Networks/internals/metrics"
 	"URL_Shortener_Ruckus_Networks/internals/service"
 	"URL_Shortener_Ruckus_Networks/internals/storage"
 
@@ -12,72 +26,249 @@ import (
 )
 
 type Handler struct {
-	service *service.URLService
+	service      *service.URLService
+	clicks       *analytics.Recorder
+	maxBatchSize int
+	maxImport    int64         // body limit of ImportLinks, see SetMaxImportBytes
+	redirect     int           // status of links without their own, see SetRedirectStatus
+	permanentAge time.Duration // Cache-Control max-age of permanent redirects
+	metrics      *metrics.Server
+	keys         auth.KeyStore
+	proxies      []netip.Prefix
+	tenants      map[string]tenantService // by id, see AddTenant
+	hosts        map[string]tenantService // by normalized host
+	logger       *slog.Logger
+	draining     atomic.Bool
 }
 
-// creating new handler instance
-func NewHandler(service *service.URLService) *Handler {
+// DefaultPermanentMaxAge is how long clients may cache a 301 or 308 redirect
+// unless SetPermanentMaxAge is called
+const DefaultPermanentMaxAge = 24 * time.Hour
+
+// creating new handler instance, clicks may be nil to disable analytics and
+// logger nil uses slog.Default()
+func NewHandler(service *service.URLService, clicks *analytics.Recorder, logger *slog.Logger) *Handler {
 	return &Handler{
-		service: service,
+		service:      service,
+		clicks:       clicks,
+		maxBatchSize: DefaultMaxBatchSize,
+		maxImport:    DefaultMaxImportBytes,
+		redirect:     http.StatusFound,
+		permanentAge: DefaultPermanentMaxAge,
+		logger:       logging.OrDefault(logger).With("component", "handler"),
 	}
 }
 
+// SetMaxBatchSize caps the number of items accepted by ShortenBatch
+func (h *Handler) SetMaxBatchSize(n int) {
+	h.maxBatchSize = n
+}
+
+// SetMaxImportBytes caps the size of a body accepted by ImportLinks
+func (h *Handler) SetMaxImportBytes(n int64) {
+	h.maxImport = n
+}
+
+// SetRedirectStatus sets the status of redirects for links shortened without
+// a redirect_type; one of 301, 302, 307 or 308
+func (h *Handler) SetRedirectStatus(status int) {
+	h.redirect = status
+}
+
+// SetPermanentMaxAge sets how long clients may cache 301 and 308 redirects
+func (h *Handler) SetPermanentMaxAge(d time.Duration) {
+	h.permanentAge = d
+}
+
+// SetMetrics counts shorten and redirect outcomes in m, nil disables counting
+func (h *Handler) SetMetrics(m *metrics.Server) {
+	h.metrics = m
+}
+
 // ShortenRequest handler
 type ShortenRequest struct {
-	URL string `json:"url"`
+	URL        string `json:"url"`
+	Alias      string `json:"alias,omitempty"`
+	TTLSeconds int64  `json:"ttl_seconds,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"` // RFC 3339
+	Domain     string `json:"domain,omitempty"`     // one of the allowed short domains
+	// RedirectType is the status redirects answer with: 301 or 308 for
+	// permanent links, 302 or 307 for temporary ones, zero for the default
+	RedirectType int `json:"redirect_type,omitempty"`
 }
 
 // ShortenResponse handler
 type ShortenResponse struct {
-	ShortURL string `json:"short_url"`
-	LongURL  string `json:"long_url"`
+	ShortURL     string `json:"short_url"`
+	LongURL      string `json:"long_url"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	RedirectType int    `json:"redirect_type,omitempty"` // the link's own status, absent for the server default
+}
+
+// LinkResponse handler - metadata of a single link
+type LinkResponse struct {
+	ShortCode string   `json:"short_code"`
+	ShortURL  string   `json:"short_url"`
+	LongURL   string   `json:"long_url"`
+	CreatedAt string   `json:"created_at"`
+	ExpiresAt string   `json:"expires_at,omitempty"`
+	Expired   bool     `json:"expired"`
+	Domains   []string `json:"domains,omitempty"` // empty when served on every domain
+	// RedirectType is the link's own redirect status, absent for the server default
+	RedirectType int `json:"redirect_type,omitempty"`
+}
+
+// ListLinksResponse handler
+type ListLinksResponse struct {
+	Links      []LinkResponse `json:"links"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// UpdateLinkRequest handler
+type UpdateLinkRequest struct {
+	URL string `json:"url"`
+}
+
+// StatsResponse handler
+type StatsResponse struct {
+	ShortCode string `json:"short_code"`
+	analytics.Stats
 }
 
 // ErrorResponse handle
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
 }
 
+// machine-readable error codes carried in ErrorResponse.Code
+const (
+	CodeURLRequired     = "url_required"
+	CodeInvalidURL      = "invalid_url"
+	CodeInvalidAlias    = "invalid_alias"
+	CodeAliasTaken      = "alias_taken"
+	CodeInvalidExpiry   = "invalid_expiry"
+	CodeInvalidDomain   = "invalid_domain"
+	CodeInvalidRedirect = "invalid_redirect_type"
+	CodeTimeout         = "timeout"
+	CodeInternal        = "internal"
+)
+
+// nginx's status for a client that went away before the response; it is
+// never written, only used to mark a request that should be dropped
+const statusClientClosedRequest = 499
+
 // ShortenURL API - POST /api/shorten
 func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 	var req ShortenRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("handler: ShortenURL - invalid request body: %v", err)
+		h.logger.Debug("ShortenURL invalid request body", "err", err)
+		h.countShorten(metrics.OutcomeInvalid)
 		h.sendError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("handler: ShortenURL - incoming URL=%s", req.URL)
+	response, reqErr := h.shorten(r.Context(), h.tenantFor(r).service, req)
+	if reqErr != nil {
+		h.sendRequestError(w, reqErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// requestError - a failed request mapped to its HTTP response
+type requestError struct {
+	message string
+	code    string
+	status  int
+}
+
+// shorten runs a single shorten request, shared by the single and batch APIs,
+// and counts its outcome
+func (h *Handler) shorten(ctx context.Context, svc *service.URLService, req ShortenRequest) (ShortenResponse, *requestError) {
+	response, created, reqErr := h.shortenLink(ctx, svc, req)
+	switch {
+	case reqErr == nil && created:
+		h.countShorten(metrics.OutcomeCreated)
+	case reqErr == nil:
+		h.countShorten(metrics.OutcomeExisting)
+	case reqErr.status == http.StatusBadRequest:
+		h.countShorten(metrics.OutcomeInvalid)
+	case reqErr.status == http.StatusConflict:
+		h.countShorten(metrics.OutcomeConflict)
+	default:
+		h.countShorten(metrics.OutcomeError)
+	}
+	return response, reqErr
+}
+
+// shortenLink validates req and shortens it, reporting whether a new link was stored
+func (h *Handler) shortenLink(ctx context.Context, svc *service.URLService, req ShortenRequest) (ShortenResponse, bool, *requestError) {
+	h.logger.Debug("ShortenURL incoming", logging.URL("url", req.URL))
 
 	if req.URL == "" {
-		log.Printf("handler: ShortenURL - empty URL")
-		h.sendError(w, "URL is required", http.StatusBadRequest)
-		return
+		h.logger.Debug("ShortenURL empty URL")
+		return ShortenResponse{}, false, &requestError{"URL is required", CodeURLRequired, http.StatusBadRequest}
+	}
+
+	expiresAt, err := parseExpiry(req)
+	if err != nil {
+		h.logger.Debug("ShortenURL invalid expiry", "err", err)
+		return ShortenResponse{}, false, &requestError{"Set either ttl_seconds (> 0) or expires_at (RFC 3339), not both", CodeInvalidExpiry, http.StatusBadRequest}
 	}
 
-	shortURL, _, err := h.service.ShortenURL(req.URL)
+	opts := service.ShortenOptions{Alias: req.Alias, ExpiresAt: expiresAt, Domain: req.Domain, RedirectStatus: req.RedirectType}
+	result, err := svc.ShortenLink(ctx, req.URL, opts)
 	if err != nil {
 		if err == service.ErrInvalidURL {
-			log.Printf("handler: ShortenURL - invalid URL format: %s", req.URL)
-			h.sendError(w, "Invalid URL format", http.StatusBadRequest)
-			return
+			h.logger.Debug("ShortenURL invalid URL format", logging.URL("url", req.URL))
+			return ShortenResponse{}, false, &requestError{"Invalid URL format", CodeInvalidURL, http.StatusBadRequest}
 		}
-		log.Printf("handler: ShortenURL - internal error: %v", err)
-		h.sendError(w, "Internal server error", http.StatusInternalServerError)
-		return
+		if err == service.ErrInvalidAlias {
+			h.logger.Debug("ShortenURL invalid alias", "alias", req.Alias)
+			return ShortenResponse{}, false, &requestError{"Alias must be 3-64 characters of letters, digits, '-' or '_' and not a reserved name", CodeInvalidAlias, http.StatusBadRequest}
+		}
+		if err == service.ErrInvalidExpiry {
+			h.logger.Debug("ShortenURL expiry in the past", "expires_at", expiresAt)
+			return ShortenResponse{}, false, &requestError{"Expiry must be in the future", CodeInvalidExpiry, http.StatusBadRequest}
+		}
+		if err == service.ErrInvalidDomain {
+			h.logger.Debug("ShortenURL domain not allowed", "domain", req.Domain)
+			return ShortenResponse{}, false, &requestError{"Domain must be one of " + strings.Join(svc.Domains(), ", "), CodeInvalidDomain, http.StatusBadRequest}
+		}
+		if err == service.ErrInvalidRedirect {
+			h.logger.Debug("ShortenURL invalid redirect type", "redirect_type", req.RedirectType)
+			return ShortenResponse{}, false, &requestError{"Redirect type must be 301, 302, 307 or 308", CodeInvalidRedirect, http.StatusBadRequest}
+		}
+		if err == service.ErrAliasTaken {
+			h.logger.Debug("ShortenURL alias taken", "alias", req.Alias)
+			return ShortenResponse{}, false, &requestError{"Alias is already in use", CodeAliasTaken, http.StatusConflict}
+		}
+		if reqErr := contextError(err); reqErr != nil {
+			h.logger.Warn("ShortenURL request ended", logging.URL("url", req.URL), "err", err)
+			return ShortenResponse{}, false, reqErr
+		}
+		h.logger.Error("ShortenURL internal error", "err", err)
+		return ShortenResponse{}, false, &requestError{"Internal server error", CodeInternal, http.StatusInternalServerError}
 	}
 
 	response := ShortenResponse{
-		ShortURL: shortURL,
-		LongURL:  req.URL,
+		ShortURL:     result.ShortURL,
+		LongURL:      req.URL,
+		RedirectType: result.RedirectStatus,
+	}
+	if !result.ExpiresAt.IsZero() {
+		response.ExpiresAt = result.ExpiresAt.UTC().Format(time.RFC3339)
 	}
 
-	log.Printf("handler: ShortenURL - created short_url=%s for long_url=%s", shortURL, req.URL)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	if result.Created {
+		h.logger.Info("ShortenURL created", logging.URL("short_url", result.ShortURL), logging.URL("long_url", req.URL))
+	}
+	return response, result.Created, nil
 }
 
 // RedirectURL API - GET /{shortCode}
@@ -85,33 +276,351 @@ func (h *Handler) RedirectURL(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	shortCode := vars["shortCode"]
 
-	log.Printf("handler: RedirectURL - shortCode=%s method=%s", shortCode, r.Method)
+	h.logger.Debug("RedirectURL", "short_code", shortCode, "method", r.Method)
+
+	status := h.redirect
+	defer func() { h.countRedirect(status) }()
 
 	if shortCode == "" {
-		log.Printf("handler: RedirectURL - missing short code")
-		h.sendError(w, "Short code is required", http.StatusBadRequest)
+		h.logger.Debug("RedirectURL missing short code")
+		status = http.StatusBadRequest
+		h.sendError(w, "Short code is required", status)
 		return
 	}
 
-	longURL, err := h.service.GetLongURL(shortCode)
+	t := h.tenantFor(r)
+	link, err := t.service.Resolve(r.Context(), r.Host, shortCode)
 	if err != nil {
+		if h.sendContextError(w, "RedirectURL", err) {
+			status = contextError(err).status
+			return
+		}
 		if err == storage.ErrNotFound {
-			log.Printf("handler: RedirectURL - not found shortCode=%s", shortCode)
-			h.sendError(w, "Short URL not found", http.StatusNotFound)
+			h.logger.Debug("RedirectURL not found", "short_code", shortCode)
+			status = http.StatusNotFound
+			h.sendError(w, "Short URL not found", status)
+			return
+		}
+		if err == storage.ErrExpired {
+			h.logger.Debug("RedirectURL expired", "short_code", shortCode)
+			status = http.StatusGone
+			h.sendError(w, "Short URL has expired", status)
 			return
 		}
-		log.Printf("handler: RedirectURL - internal error retrieving shortCode=%s: %v", shortCode, err)
+		h.logger.Error("RedirectURL internal error", "short_code", shortCode, "err", err)
+		status = http.StatusInternalServerError
+		h.sendError(w, "Internal server error", status)
+		return
+	}
+
+	if link.RedirectStatus != 0 {
+		status = link.RedirectStatus
+	}
+	w.Header().Set("Cache-Control", h.redirectCacheControl(status, link))
+
+	h.logger.Debug("RedirectURL redirecting", "short_code", shortCode, logging.URL("long_url", link.LongURL), "status", status)
+	http.Redirect(w, r, link.LongURL, status)
+
+	if h.clicks != nil {
+		h.clicks.Record(analytics.Event{
+			Timestamp: time.Now(),
+			Tenant:    t.id,
+			ShortCode: shortCode,
+			Referrer:  r.Referer(),
+			UserAgent: r.UserAgent(),
+			ClientIP:  h.clientIP(r),
+			Method:    r.Method,
+		})
+	}
+}
+
+// LinkStats API - GET /api/links/{shortCode}/stats
+func (h *Handler) LinkStats(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["shortCode"]
+
+	t := h.tenantFor(r)
+	if _, err := t.service.GetLink(r.Context(), shortCode); err != nil {
+		h.sendLinkError(w, "LinkStats", shortCode, err)
+		return
+	}
+
+	response := StatsResponse{ShortCode: shortCode, Stats: analytics.Stats{Daily: []analytics.DayCount{}}}
+	if h.clicks != nil {
+		response.Stats = h.clicks.TenantStats(t.id, shortCode)
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+// ListLinks API - GET /api/links?cursor=&limit=&q=&host=
+func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	opts := storage.ListOptions{
+		Cursor: query.Get("cursor"),
+		Query:  query.Get("q"),
+		Host:   query.Get("host"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > storage.MaxListLimit {
+			h.logger.Debug("ListLinks invalid limit", "limit", limit)
+			h.sendError(w, fmt.Sprintf("limit must be between 1 and %d", storage.MaxListLimit), http.StatusBadRequest)
+			return
+		}
+		opts.Limit = n
+	}
+
+	svc := h.tenantFor(r).service
+	page, err := svc.ListLinks(r.Context(), opts)
+	if err != nil {
+		if h.sendContextError(w, "ListLinks", err) {
+			return
+		}
+		if err == storage.ErrInvalidCursor {
+			h.logger.Debug("ListLinks invalid cursor", "cursor", opts.Cursor)
+			h.sendError(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("ListLinks internal error", "err", err)
 		h.sendError(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("handler: RedirectURL - redirecting shortCode=%s -> %s", shortCode, longURL)
-	http.Redirect(w, r, longURL, http.StatusFound)
+	response := ListLinksResponse{
+		Links:      make([]LinkResponse, 0, len(page.Links)),
+		NextCursor: page.NextCursor,
+	}
+	for _, link := range page.Links {
+		response.Links = append(response.Links, linkResponse(svc, link))
+	}
+
+	h.logger.Debug("ListLinks", "returned", len(response.Links), "q", opts.Query, "host", opts.Host)
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+// GetLink API - GET /api/links/{shortCode}
+func (h *Handler) GetLink(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["shortCode"]
+
+	svc := h.tenantFor(r).service
+	link, err := svc.GetLink(r.Context(), shortCode)
+	if err != nil {
+		h.sendLinkError(w, "GetLink", shortCode, err)
+		return
+	}
+
+	h.sendJSON(w, linkResponse(svc, link), http.StatusOK)
 }
 
-// sendError
-func (h *Handler) sendError(w http.ResponseWriter, message string, statusCode int) {
+// UpdateLink API - PATCH /api/links/{shortCode}
+func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["shortCode"]
+
+	var req UpdateLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Debug("UpdateLink invalid request body", "err", err)
+		h.sendError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.URL == "" {
+		h.logger.Debug("UpdateLink empty URL")
+		h.sendError(w, "URL is required", http.StatusBadRequest)
+		return
+	}
+
+	svc := h.tenantFor(r).service
+	link, err := svc.UpdateLink(r.Context(), shortCode, req.URL)
+	if err != nil {
+		if err == service.ErrInvalidURL {
+			h.logger.Debug("UpdateLink invalid URL format", logging.URL("url", req.URL))
+			h.sendError(w, "Invalid URL format", http.StatusBadRequest)
+			return
+		}
+		h.sendLinkError(w, "UpdateLink", shortCode, err)
+		return
+	}
+
+	h.logger.Info("UpdateLink retargeted", "short_code", shortCode, logging.URL("url", req.URL))
+	h.sendJSON(w, linkResponse(svc, link), http.StatusOK)
+}
+
+// DeleteLink API - DELETE /api/links/{shortCode}
+func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	shortCode := mux.Vars(r)["shortCode"]
+
+	if err := h.tenantFor(r).service.DeleteLink(r.Context(), shortCode); err != nil {
+		h.sendLinkError(w, "DeleteLink", shortCode, err)
+		return
+	}
+
+	h.logger.Info("DeleteLink deleted", "short_code", shortCode)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// linkResponse - API view of a link stored by svc
+func linkResponse(svc *service.URLService, link storage.Link) LinkResponse {
+	resp := LinkResponse{
+		ShortCode:    link.ShortCode,
+		ShortURL:     svc.LinkURL(link),
+		LongURL:      link.LongURL,
+		CreatedAt:    link.CreatedAt.UTC().Format(time.RFC3339),
+		Expired:      link.Expired(time.Now()),
+		Domains:      link.Domains,
+		RedirectType: link.RedirectStatus,
+	}
+	if !link.ExpiresAt.IsZero() {
+		resp.ExpiresAt = link.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+// sendLinkError - 404 for unknown codes, 504 past the deadline, 500 otherwise
+func (h *Handler) sendLinkError(w http.ResponseWriter, op, shortCode string, err error) {
+	if h.sendContextError(w, op, err) {
+		return
+	}
+	if err == storage.ErrNotFound {
+		h.logger.Debug("link not found", "op", op, "short_code", shortCode)
+		h.sendError(w, "Short URL not found", http.StatusNotFound)
+		return
+	}
+	h.logger.Error("link internal error", "op", op, "short_code", shortCode, "err", err)
+	h.sendError(w, "Internal server error", http.StatusInternalServerError)
+}
+
+// contextError maps a request that timed out or was abandoned by its client
+// to its response, nil for any other error
+func contextError(err error) *requestError {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &requestError{"Request timed out", CodeTimeout, http.StatusGatewayTimeout}
+	}
+	if errors.Is(err, context.Canceled) {
+		return &requestError{"Client closed request", "", statusClientClosedRequest}
+	}
+	return nil
+}
+
+// sendContextError answers a context error and reports whether err was one
+func (h *Handler) sendContextError(w http.ResponseWriter, op string, err error) bool {
+	reqErr := contextError(err)
+	if reqErr == nil {
+		return false
+	}
+	h.logger.Warn("request ended", "op", op, "err", err)
+	h.sendRequestError(w, reqErr)
+	return true
+}
+
+// sendRequestError writes reqErr, or nothing once the client has gone away
+func (h *Handler) sendRequestError(w http.ResponseWriter, reqErr *requestError) {
+	if reqErr.status == statusClientClosedRequest {
+		h.logger.Debug("client disconnected, response dropped")
+		return
+	}
+	h.sendErrorCode(w, reqErr.message, reqErr.code, reqErr.status)
+}
+
+// countShorten counts a shorten request, if metrics are enabled
+func (h *Handler) countShorten(outcome string) {
+	if h.metrics != nil {
+		h.metrics.Shorten(outcome)
+	}
+}
+
+// redirectCacheControl lets clients keep a permanent redirect for the
+// permanent max-age, or until the link expires if that is sooner. Temporary
+// redirects are never stored, so retargeting a link takes effect at once.
+func (h *Handler) redirectCacheControl(status int, link storage.Link) string {
+	if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect {
+		return "no-store"
+	}
+	maxAge := h.permanentAge
+	if !link.ExpiresAt.IsZero() {
+		maxAge = min(maxAge, time.Until(link.ExpiresAt))
+	}
+	return fmt.Sprintf("public, max-age=%d", int64(max(maxAge, 0)/time.Second))
+}
+
+// countRedirect counts a redirect request, if metrics are enabled
+func (h *Handler) countRedirect(status int) {
+	if h.metrics != nil {
+		h.metrics.Redirect(status)
+	}
+}
+
+// clientIP - remote address of the request without the port. When the peer
+// is a trusted proxy, X-Forwarded-For is walked from the right and the first
+// address not in a trusted range is the client.
+func (h *Handler) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !h.trustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		host = hop
+		if !h.trustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// trustedProxy reports whether addr falls in a SetTrustedProxies range
+func (h *Handler) trustedProxy(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range h.proxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExpiry turns ttl_seconds / expires_at into an absolute time, zero if neither is set
+func parseExpiry(req ShortenRequest) (time.Time, error) {
+	if req.TTLSeconds != 0 && req.ExpiresAt != "" {
+		return time.Time{}, errors.New("both ttl_seconds and expires_at set")
+	}
+	if req.TTLSeconds < 0 || req.TTLSeconds > math.MaxInt64/int64(time.Second) {
+		return time.Time{}, errors.New("ttl_seconds out of range")
+	}
+	if req.TTLSeconds > 0 {
+		return time.Now().Add(time.Duration(req.TTLSeconds) * time.Second), nil
+	}
+	if req.ExpiresAt != "" {
+		return time.Parse(time.RFC3339, req.ExpiresAt)
+	}
+	return time.Time{}, nil
+}
+
+// sendJSON
+func (h *Handler) sendJSON(w http.ResponseWriter, body interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	json.NewEncoder(w).Encode(body)
+}
+
+// sendError
+func (h *Handler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	h.sendErrorCode(w, message, "", statusCode)
+}
+
+// sendErrorCode - sendError with a machine-readable code
+func (h *Handler) sendErrorCode(w http.ResponseWriter, message, code string, statusCode int) {
+	h.sendJSON(w, ErrorResponse{Error: message, Code: code}, statusCode)
 }
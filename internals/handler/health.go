@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// longest a readiness probe waits on storage
+const readyTimeout = 2 * time.Second
+
+// HealthResponse handler - liveness and readiness probe body
+type HealthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Healthz API - GET /healthz
+//
+// Answers 200 while the process is serving, whatever the storage state.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	h.sendJSON(w, HealthResponse{Status: "ok"}, http.StatusOK)
+}
+
+// Readyz API - GET /readyz
+//
+// Answers 200 when every tenant's storage backend can serve requests, and
+// 503 while one cannot or once the server has started draining.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		h.sendJSON(w, HealthResponse{Status: "draining"}, http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	if err := h.service.Ping(ctx); err != nil {
+		h.logger.Warn("Readyz storage not ready", "err", err)
+		h.sendJSON(w, HealthResponse{Status: "unavailable", Error: err.Error()}, http.StatusServiceUnavailable)
+		return
+	}
+	for id, t := range h.tenants {
+		if err := t.service.Ping(ctx); err != nil {
+			h.logger.Warn("Readyz storage not ready", "tenant", id, "err", err)
+			h.sendJSON(w, HealthResponse{Status: "unavailable", Error: "tenant " + id + ": " + err.Error()}, http.StatusServiceUnavailable)
+			return
+		}
+	}
+	h.sendJSON(w, HealthResponse{Status: "ready"}, http.StatusOK)
+}
+
+// Drain makes /readyz fail from now on, so load balancers stop sending new
+// requests while in-flight ones finish
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
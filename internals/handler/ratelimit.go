@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/auth"
+	"URL_Shortener_Ruckus_Networks/internals/ratelimit"
+)
+
+// error code for a request over its rate limit
+const CodeRateLimited = "rate_limited"
+
+// requestLimit is the limiter and client Limit charged a request to, kept in
+// its context so a handler doing several items of work can charge the rest
+type requestLimit struct {
+	limiter *ratelimit.Limiter
+	client  string
+}
+
+type requestLimitKey struct{}
+
+// SetTrustedProxies makes clientIP believe X-Forwarded-For when the request
+// comes from one of proxies, nil trusts no one
+func (h *Handler) SetTrustedProxies(proxies []netip.Prefix) {
+	h.proxies = proxies
+}
+
+// Limit wraps next so each client may only make the requests limiter
+// allows, answering 429 otherwise. Clients are told apart by API key when
+// Authenticate has run, else by IP. The request takes one token; next may
+// take more through charge. A nil limiter passes every request.
+func (h *Handler) Limit(limiter *ratelimit.Limiter, next http.HandlerFunc) http.HandlerFunc {
+	if limiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := "ip:" + h.clientIP(r)
+		if key, ok := auth.KeyFromContext(r.Context()); ok {
+			client = "key:" + key.ID
+		}
+
+		rl := requestLimit{limiter: limiter, client: client}
+		if d := h.takeTokens(w, r, rl, 1); !d.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(d.RetryAfter))))
+			h.sendErrorCode(w, "Rate limit exceeded", CodeRateLimited, http.StatusTooManyRequests)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), requestLimitKey{}, rl)))
+	}
+}
+
+// charge takes n more tokens for r from the limit Limit applied to it, all or
+// none. A request Limit did not wrap is always allowed.
+func (h *Handler) charge(w http.ResponseWriter, r *http.Request, n int) ratelimit.Decision {
+	rl, ok := r.Context().Value(requestLimitKey{}).(requestLimit)
+	if !ok || n < 1 {
+		return ratelimit.Decision{Allowed: true}
+	}
+	return h.takeTokens(w, r, rl, n)
+}
+
+// takeTokens takes n tokens from rl, setting the RateLimit headers while
+// they can still be sent and counting a refusal
+func (h *Handler) takeTokens(w http.ResponseWriter, r *http.Request, rl requestLimit, n int) ratelimit.Decision {
+	d := rl.limiter.AllowN(rl.client, n)
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	if !d.Allowed {
+		h.logger.Info("Limit exceeded", "limit", rl.limiter.Name(), "client", rl.client, "path", r.URL.Path, "tokens", n)
+		if h.metrics != nil {
+			h.metrics.RateLimited(rl.limiter.Name())
+		}
+	}
+	return d
+}
+
+// ceilSeconds - d in whole seconds, rounded up as the RateLimit headers expect
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
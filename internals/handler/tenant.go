@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"URL_Shortener_Ruckus_Networks/internals/auth"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/tenant"
+)
+
+// tenantService is the service holding one tenant's links
+type tenantService struct {
+	id      string // empty for the default tenant
+	service *service.URLService
+}
+
+// AddTenant serves tenant id from svc, which should have its own storage
+// and base URL. Redirects on one of hosts and API requests with a key of
+// the tenant go to svc; everything else goes to the service NewHandler got.
+// Add every tenant before serving.
+func (h *Handler) AddTenant(id string, hosts []string, svc *service.URLService) error {
+	if id == "" {
+		return fmt.Errorf("%w: id is required", tenant.ErrInvalidTenant)
+	}
+	if _, dup := h.tenants[id]; dup {
+		return fmt.Errorf("%w: tenant %s: id already in use", tenant.ErrInvalidTenant, id)
+	}
+	for _, host := range hosts {
+		if owner, taken := h.hosts[tenant.NormalizeHost(host)]; taken {
+			return fmt.Errorf("%w: tenant %s: host %s already belongs to %s", tenant.ErrInvalidTenant, id, host, owner.id)
+		}
+	}
+
+	if h.tenants == nil {
+		h.tenants = make(map[string]tenantService)
+		h.hosts = make(map[string]tenantService)
+	}
+	t := tenantService{id: id, service: svc}
+	h.tenants[id] = t
+	for _, host := range hosts {
+		h.hosts[tenant.NormalizeHost(host)] = t
+	}
+	return nil
+}
+
+// tenantFor picks the tenant serving r: the authenticated key's tenant, else
+// the one owning the Host header, else the default tenant
+func (h *Handler) tenantFor(r *http.Request) tenantService {
+	if key, ok := auth.KeyFromContext(r.Context()); ok {
+		if t, ok := h.tenants[key.Tenant]; ok {
+			return t
+		}
+		return tenantService{service: h.service}
+	}
+	if t, ok := h.hosts[tenant.NormalizeHost(r.Host)]; ok {
+		return t
+	}
+	return tenantService{service: h.service}
+}
+
+// knownTenant reports whether id is the default tenant or was added
+func (h *Handler) knownTenant(id string) bool {
+	_, ok := h.tenants[id]
+	return id == "" || ok
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout is middleware giving every request a deadline of d. Storage calls
+// still running at the deadline give up and the request is answered with 504.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// streamResponse readies w for a streamed response, which may rightly run
+// past the server's WriteTimeout: the write deadline is lifted, leaving the
+// request deadline and the client going away to end the stream. With
+// fullDuplex the request body stays readable while results are written, and
+// is no longer held to ReadTimeout either. Writers without that control,
+// such as test recorders, are left as they are.
+func streamResponse(w http.ResponseWriter, fullDuplex bool) {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	if fullDuplex {
+		rc.SetReadDeadline(time.Time{})
+		rc.EnableFullDuplex()
+	}
+}
@@ -0,0 +1,90 @@
+// Package logging builds the service's slog.Logger from the environment
+// and keeps URLs in log records free of query strings.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// output formats selectable by name
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures New
+type Options struct {
+	Format string     // FormatText or FormatJSON
+	Level  slog.Level // records below this level are dropped
+}
+
+// ParseOptions converts LOG_FORMAT / LOG_LEVEL style values into Options,
+// defaulting to text at info level
+func ParseOptions(format, level string) (Options, error) {
+	opts := Options{Format: FormatText, Level: slog.LevelInfo}
+
+	switch f := strings.ToLower(format); f {
+	case "":
+	case FormatText, FormatJSON:
+		opts.Format = f
+	default:
+		return opts, fmt.Errorf("unknown log format %q", format)
+	}
+
+	if level != "" {
+		if err := opts.Level.UnmarshalText([]byte(level)); err != nil {
+			return opts, fmt.Errorf("unknown log level %q", level)
+		}
+	}
+	return opts, nil
+}
+
+// New returns a logger writing opts.Format records at opts.Level or above to w
+func New(w io.Writer, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	if opts.Format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, handlerOpts))
+	}
+	return slog.New(slog.NewTextHandler(w, handlerOpts))
+}
+
+// OrDefault returns logger, or slog.Default() when logger is nil
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// URL is a log attribute holding rawURL with its query string, fragment
+// and password redacted, since those often carry tokens
+func URL(key, rawURL string) slog.Attr {
+	return slog.String(key, RedactURL(rawURL))
+}
+
+// RedactURL replaces the query string and fragment of rawURL with
+// "REDACTED" and masks any password. Input that does not parse is cut at
+// the first '?' or '#'.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+			return rawURL[:i] + "?REDACTED"
+		}
+		return rawURL
+	}
+
+	if u.RawQuery != "" || u.ForceQuery {
+		u.RawQuery = "REDACTED"
+		u.ForceQuery = false
+	}
+	if u.Fragment != "" {
+		u.Fragment = "REDACTED"
+		u.RawFragment = ""
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// outcomes of a shorten request
+const (
+	OutcomeCreated  = "created"  // a new short code was stored
+	OutcomeExisting = "existing" // the URL or alias was already shortened
+	OutcomeInvalid  = "invalid"  // the request was rejected as malformed
+	OutcomeConflict = "conflict" // the alias belongs to another URL
+	OutcomeError    = "error"    // storage failed or the request ran out of time
+)
+
+// Server holds the series reported by the HTTP server
+type Server struct {
+	*Registry
+
+	shortens    *Counter
+	redirects   *Counter
+	rateLimited *Counter
+	latency     *Histogram
+}
+
+// NewServer registers the server's request metrics in a new registry.
+// Further gauges, such as storage size, are added to the same registry.
+func NewServer() *Server {
+	r := NewRegistry()
+	return &Server{
+		Registry:    r,
+		shortens:    r.NewCounter("urlshortener_shorten_requests_total", "Shorten requests by outcome, batch items included.", "outcome"),
+		redirects:   r.NewCounter("urlshortener_redirects_total", "Redirect requests by response status.", "status"),
+		rateLimited: r.NewCounter("urlshortener_rate_limited_total", "Requests rejected with 429 by rate limit.", "limit"),
+		latency:     r.NewHistogram("urlshortener_http_request_duration_seconds", "Request latency by route.", nil, "route", "method"),
+	}
+}
+
+// Shorten counts a shorten request with the given outcome
+func (s *Server) Shorten(outcome string) {
+	s.shortens.Inc(outcome)
+}
+
+// ShortenCount returns how many shorten requests ended with outcome
+func (s *Server) ShortenCount(outcome string) uint64 {
+	return s.shortens.Value(outcome)
+}
+
+// Redirect counts a redirect request answered with status
+func (s *Server) Redirect(status int) {
+	s.redirects.Inc(strconv.Itoa(status))
+}
+
+// RedirectCount returns how many redirect requests were answered with status
+func (s *Server) RedirectCount(status int) uint64 {
+	return s.redirects.Value(strconv.Itoa(status))
+}
+
+// RateLimited counts a request rejected by the named rate limit
+func (s *Server) RateLimited(limit string) {
+	s.rateLimited.Inc(limit)
+}
+
+// RateLimitedCount returns how many requests the named rate limit rejected
+func (s *Server) RateLimitedCount(limit string) uint64 {
+	return s.rateLimited.Value(limit)
+}
+
+// RequestCount returns how many requests to route, a path template as
+// registered on the router, were timed
+func (s *Server) RequestCount(route, method string) uint64 {
+	return s.latency.Count(route, method)
+}
+
+// Middleware times every request by the path template of the route it
+// matched, so /{shortCode} is one series however many codes are served
+func (s *Server) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		s.latency.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
@@ -0,0 +1,177 @@
+// Package ratelimit implements per-client token buckets. Each client starts
+// with a full bucket of Burst tokens, every request takes one (or n, for work
+// weighed by AllowN), and tokens refill at Requests per Per.
+package ratelimit
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures a Limiter
+type Options struct {
+	Name       string        // names the limit in logs and metrics, e.g. "shorten"
+	Requests   int           // tokens refilled every Per
+	Per        time.Duration // refill period
+	Burst      int           // bucket size, 0 means Requests
+	MaxClients int           // buckets kept before the least recently seen is dropped, 0 means DefaultMaxClients
+}
+
+// DefaultMaxClients bounds the client table when Options.MaxClients is unset
+const DefaultMaxClients = 10000
+
+// Decision is the outcome of a single Allow or AllowN
+type Decision struct {
+	Allowed    bool
+	Limit      int           // bucket size
+	Remaining  int           // whole tokens left after this request
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, zero when allowed
+}
+
+// bucket is one client's tokens as of updated
+type bucket struct {
+	client  string
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter keeps one token bucket per client. The table is an LRU bounded by
+// MaxClients; a dropped client simply starts again with a full bucket.
+type Limiter struct {
+	opts     Options
+	perToken time.Duration // refill time of a single token
+
+	mu      sync.Mutex
+	clients map[string]*list.Element
+	lru     *list.List // front is most recently seen
+}
+
+// New creates a limiter, rejecting a rate that never refills
+func New(opts Options) (*Limiter, error) {
+	if opts.Requests < 1 || opts.Per < time.Duration(opts.Requests) {
+		return nil, fmt.Errorf("ratelimit: %s: invalid rate %d per %s", opts.Name, opts.Requests, opts.Per)
+	}
+	if opts.Burst < 1 {
+		opts.Burst = opts.Requests
+	}
+	if opts.MaxClients < 1 {
+		opts.MaxClients = DefaultMaxClients
+	}
+	return &Limiter{
+		opts:     opts,
+		perToken: opts.Per / time.Duration(opts.Requests),
+		clients:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}, nil
+}
+
+// Name returns Options.Name
+func (l *Limiter) Name() string {
+	return l.opts.Name
+}
+
+// Allow takes a token from client's bucket if one is left
+func (l *Limiter) Allow(client string) Decision {
+	return l.AllowNAt(client, 1, time.Now())
+}
+
+// AllowAt is Allow with an explicit clock, for tests
+func (l *Limiter) AllowAt(client string, now time.Time) Decision {
+	return l.AllowNAt(client, 1, now)
+}
+
+// AllowN takes n tokens from client's bucket if that many are left, and none
+// otherwise. More than Burst tokens are never allowed.
+func (l *Limiter) AllowN(client string, n int) Decision {
+	return l.AllowNAt(client, n, time.Now())
+}
+
+// AllowNAt is AllowN with an explicit clock, for tests
+func (l *Limiter) AllowNAt(client string, n int, now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(client, now)
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(l.opts.Burst), b.tokens+float64(elapsed)/float64(l.perToken))
+		b.updated = now
+	}
+
+	d := Decision{Limit: l.opts.Burst}
+	if b.tokens >= float64(n) {
+		b.tokens -= float64(n)
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.refillTime(float64(n) - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.refillTime(float64(l.opts.Burst) - b.tokens)
+	return d
+}
+
+// Len returns how many clients are tracked
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lru.Len()
+}
+
+// bucket returns client's bucket, creating a full one and dropping the least
+// recently seen client when the table is full. Caller holds mu.
+func (l *Limiter) bucket(client string, now time.Time) *bucket {
+	if elem, ok := l.clients[client]; ok {
+		l.lru.MoveToFront(elem)
+		return elem.Value.(*bucket)
+	}
+
+	if l.lru.Len() >= l.opts.MaxClients {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.clients, oldest.Value.(*bucket).client)
+	}
+	b := &bucket{client: client, tokens: float64(l.opts.Burst), updated: now}
+	l.clients[client] = l.lru.PushFront(b)
+	return b
+}
+
+// refillTime - how long until tokens more have been refilled
+func (l *Limiter) refillTime(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens * float64(l.perToken)))
+}
+
+// ParseRate parses "N/unit", where unit is s, m or h, or a Go duration such
+// as "10/30s"
+func ParseRate(s string) (requests int, per time.Duration, err error) {
+	n, unit, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("ratelimit: invalid rate %q, want N/s, N/m or N/h", s)
+	}
+	requests, err = strconv.Atoi(strings.TrimSpace(n))
+	if err != nil || requests < 1 {
+		return 0, 0, fmt.Errorf("ratelimit: invalid rate %q, want at least 1 request", s)
+	}
+
+	switch unit = strings.TrimSpace(unit); unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		per, err = time.ParseDuration(unit)
+		if err != nil || per <= 0 {
+			return 0, 0, fmt.Errorf("ratelimit: invalid rate %q, want N/s, N/m or N/h", s)
+		}
+	}
+	return requests, per, nil
+}
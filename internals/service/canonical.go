@@ -0,0 +1,192 @@
+package service
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeOptions controls how long URLs are canonicalized before hashing
+// and reverse lookup, so equivalent spellings share one short code
+type NormalizeOptions struct {
+	Enabled       bool // lowercase scheme/host, punycode, strip default ports, resolve dot segments
+	SortQuery     bool // order query parameters by key
+	StripTracking bool // drop utm_* and click-id parameters
+}
+
+// DefaultNormalizeOptions applies the rules that never change what a URL
+// points at; query rewriting is opt-in
+func DefaultNormalizeOptions() NormalizeOptions {
+	return NormalizeOptions{Enabled: true}
+}
+
+// query parameters dropped by StripTracking
+var (
+	trackingPrefixes = []string{"utm_"}
+	trackingParams   = map[string]bool{
+		"fbclid":  true,
+		"gclid":   true,
+		"dclid":   true,
+		"gbraid":  true,
+		"wbraid":  true,
+		"msclkid": true,
+		"yclid":   true,
+		"igshid":  true,
+		"mc_cid":  true,
+		"mc_eid":  true,
+	}
+)
+
+// default port per scheme, dropped from the host
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL returns the canonical form of an already validated URL
+func NormalizeURL(rawURL string, opts NormalizeOptions) (string, error) {
+	if !opts.Enabled {
+		return rawURL, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	escapedPath := removeDotSegments(u.EscapedPath())
+	if escapedPath == "" {
+		escapedPath = "/"
+	}
+	if u.Path, err = url.PathUnescape(escapedPath); err != nil {
+		return "", ErrInvalidURL
+	}
+	u.RawPath = escapedPath
+
+	u.RawQuery = normalizeQuery(u.RawQuery, opts)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// idnaProfile is idna.Lookup without its strict domain-name rules, so hosts
+// such as "my_host.example.com" that resolve in practice stay valid
+var idnaProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false))
+
+// normalizeHost lowercases the host and converts IDNs to punycode
+func normalizeHost(host string) (string, error) {
+	if net.ParseIP(host) != nil {
+		return strings.ToLower(host), nil
+	}
+	ascii, err := idnaProfile.ToASCII(host)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(ascii), nil
+}
+
+// removeDotSegments resolves "." and ".." segments per RFC 3986 section 5.2.4
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		switch seg {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			// never pop the leading empty segment of an absolute path
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, seg)
+		}
+	}
+
+	return strings.Join(out, "/")
+}
+
+// normalizeQuery strips tracking parameters and sorts by key as configured,
+// leaving the encoding of every kept parameter untouched
+func normalizeQuery(rawQuery string, opts NormalizeOptions) string {
+	if rawQuery == "" || (!opts.SortQuery && !opts.StripTracking) {
+		return rawQuery
+	}
+
+	type param struct {
+		key string
+		raw string
+	}
+
+	var params []param
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		rawKey, _, _ := strings.Cut(raw, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		if opts.StripTracking && isTrackingParam(key) {
+			continue
+		}
+		params = append(params, param{key: key, raw: raw})
+	}
+
+	if opts.SortQuery {
+		// stable, so repeated keys keep their relative order
+		sort.SliceStable(params, func(i, j int) bool { return params[i].key < params[j].key })
+	}
+
+	kept := make([]string, len(params))
+	for i, p := range params {
+		kept[i] = p.raw
+	}
+	return strings.Join(kept, "&")
+}
+
+// isTrackingParam reports whether key is a known tracking parameter
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	if trackingParams[key] {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
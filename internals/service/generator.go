@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+// code generation strategies selectable by name
+const (
+	StrategyHash     = "hash"
+	StrategyCounter  = "counter"
+	StrategyRandom   = "random"
+	StrategyReadable = "readable"
+)
+
+const (
+	DefaultCodeLength = 8
+	MinCodeLength     = 4
+	MaxCodeLength     = 32
+)
+
+// alphabets for the counter and random strategies
+const (
+	Base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// no 0/O/o, 1/l/I - safe to read aloud or copy from print
+	ReadableAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz"
+)
+
+// CodeGenerator derives candidate short codes. attempt is 0 for the first
+// candidate for a URL and increments after every collision.
+type CodeGenerator interface {
+	Generate(longURL string, attempt int) (string, error)
+}
+
+// CodeGeneratorFunc adapts a plain function to CodeGenerator
+type CodeGeneratorFunc func(longURL string, attempt int) string
+
+// Generate calls f
+func (f CodeGeneratorFunc) Generate(longURL string, attempt int) (string, error) {
+	return f(longURL, attempt), nil
+}
+
+// NewCodeGenerator builds the named strategy with codes of the given length
+func NewCodeGenerator(strategy string, length int) (CodeGenerator, error) {
+	if length < MinCodeLength || length > MaxCodeLength {
+		return nil, fmt.Errorf("code length must be between %d and %d, got %d", MinCodeLength, MaxCodeLength, length)
+	}
+
+	switch strategy {
+	case "", StrategyHash:
+		return NewHashGenerator(length), nil
+	case StrategyCounter:
+		return NewCounterGenerator(length), nil
+	case StrategyRandom:
+		return NewRandomGenerator(length, Base62Alphabet), nil
+	case StrategyReadable:
+		return NewRandomGenerator(length, ReadableAlphabet), nil
+	}
+	return nil, fmt.Errorf("unknown code strategy %q", strategy)
+}
+
+// HashGenerator derives codes from the SHA-256 of the URL. The same URL always
+// yields the same candidates, so collisions resolve identically everywhere.
+type HashGenerator struct {
+	length int
+}
+
+// NewHashGenerator creates a hash generator producing codes of length characters
+func NewHashGenerator(length int) *HashGenerator {
+	return &HashGenerator{length: length}
+}
+
+// Generate hashes the URL, salted with the attempt number after a collision
+func (g *HashGenerator) Generate(longURL string, attempt int) (string, error) {
+	return hashCode(longURL, attempt, g.length), nil
+}
+
+// HashShortCode is the default 8 character hash candidate for longURL
+func HashShortCode(longURL string, attempt int) string {
+	return hashCode(longURL, attempt, DefaultCodeLength)
+}
+
+// hashCode - base64url SHA-256 of the (salted) URL, truncated to length
+func hashCode(longURL string, attempt, length int) string {
+	input := longURL
+	if attempt > 0 {
+		input = fmt.Sprintf("%s#%d", longURL, attempt)
+	}
+
+	hash := sha256.Sum256([]byte(input))
+	encoded := base64.URLEncoding.EncodeToString(hash[:])
+
+	return strings.TrimRight(encoded, "=")[:length]
+}
+
+// CounterGenerator hands out a monotonic counter in base62, left-padded to
+// length. Every call, including a retry after a collision, takes the next value.
+type CounterGenerator struct {
+	length int
+	mu     sync.Mutex
+	next   uint64
+}
+
+// NewCounterGenerator creates a counter generator starting at zero
+func NewCounterGenerator(length int) *CounterGenerator {
+	return &CounterGenerator{length: length}
+}
+
+// Generate returns the next counter value
+func (g *CounterGenerator) Generate(longURL string, attempt int) (string, error) {
+	g.mu.Lock()
+	n := g.next
+	g.next++
+	g.mu.Unlock()
+
+	return encodeBase62(n, g.length), nil
+}
+
+// Observe moves the counter past code if code is one of its own values, so a
+// restarted process does not walk through codes that are already taken
+func (g *CounterGenerator) Observe(code string) {
+	if len(code) != g.length {
+		return
+	}
+	n, ok := decodeBase62(code)
+	if !ok {
+		return
+	}
+
+	g.mu.Lock()
+	if n >= g.next {
+		g.next = n + 1
+	}
+	g.mu.Unlock()
+}
+
+// SeedCounter advances gen past every code already held by store
+func SeedCounter(ctx context.Context, gen *CounterGenerator, store storage.Storage) error {
+	opts := storage.ListOptions{Limit: storage.MaxListLimit}
+	for {
+		page, err := store.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, link := range page.Links {
+			gen.Observe(link.ShortCode)
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// encodeBase62 writes n in base62, left-padded with '0' to at least length
+func encodeBase62(n uint64, length int) string {
+	var buf []byte
+	for n > 0 {
+		buf = append(buf, Base62Alphabet[n%62])
+		n /= 62
+	}
+	for len(buf) < length {
+		buf = append(buf, Base62Alphabet[0])
+	}
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return string(buf)
+}
+
+// decodeBase62 parses a base62 string, false if it is not one
+func decodeBase62(s string) (uint64, bool) {
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(Base62Alphabet, s[i])
+		if d < 0 || n > (^uint64(0)-uint64(d))/62 {
+			return 0, false
+		}
+		n = n*62 + uint64(d)
+	}
+	return n, true
+}
+
+// RandomGenerator draws every character uniformly from alphabet using
+// crypto/rand, so codes reveal nothing about the URL or creation order
+type RandomGenerator struct {
+	length   int
+	alphabet string
+}
+
+// NewRandomGenerator creates a random generator over alphabet
+func NewRandomGenerator(length int, alphabet string) *RandomGenerator {
+	return &RandomGenerator{length: length, alphabet: alphabet}
+}
+
+// Generate draws a fresh random code
+func (g *RandomGenerator) Generate(longURL string, attempt int) (string, error) {
+	max := big.NewInt(int64(len(g.alphabet)))
+	buf := make([]byte, g.length)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = g.alphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
@@ -1,99 +1,455 @@
 package service
 
 import (
-	"crypto/sha256"
-	"encoding/base64"
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/url"
+	"slices"
 	"strings"
+	"time"
 
+	"URL_Shortener_Ruckus_Networks/internals/logging"
 	"URL_Shortener_Ruckus_Networks/internals/storage"
+	"URL_Shortener_Ruckus_Networks/internals/tenant"
 )
 
 var (
-	ErrInvalidURL = errors.New("invalid URL format")
+	ErrInvalidURL         = errors.New("invalid URL format")
+	ErrCodeSpaceExhausted = errors.New("no free short code found")
+	ErrInvalidAlias       = errors.New("invalid alias")
+	ErrAliasTaken         = errors.New("alias already in use")
+	ErrInvalidExpiry      = errors.New("expiry must be in the future")
+	ErrInvalidDomain      = errors.New("domain not allowed")
+	ErrInvalidRedirect    = errors.New("redirect type must be 301, 302, 307 or 308")
 )
 
+// alias rules - the code rules of storage.ValidCode
+const (
+	MinAliasLength = storage.MinCodeLength
+	MaxAliasLength = storage.MaxCodeLength
+)
+
+// number of candidate codes tried for a URL before giving up
+const maxCodeAttempts = 16
+
 // business logic struct for URL shortening service
 type URLService struct {
-	storage storage.Storage
-	baseURL string
+	storage   storage.Storage
+	baseURL   string
+	baseHost  string          // normalized host of baseURL
+	domains   map[string]bool // extra short domains allowed, besides baseHost
+	generator CodeGenerator
+	normalize NormalizeOptions
+	logger    *slog.Logger
 }
 
-// creates a new URL service
-func NewURLService(storage storage.Storage, baseURL string) *URLService {
+// creates a new URL service, generator nil uses 8 character hash codes and
+// logger nil uses slog.Default()
+func NewURLService(storage storage.Storage, baseURL string, generator CodeGenerator, logger *slog.Logger) *URLService {
+	if generator == nil {
+		generator = NewHashGenerator(DefaultCodeLength)
+	}
+	var baseHost string
+	if u, err := url.Parse(baseURL); err == nil {
+		baseHost = tenant.NormalizeHost(u.Host)
+	}
 	return &URLService{
-		storage: storage,
-		baseURL: baseURL,
+		storage:   storage,
+		baseURL:   baseURL,
+		baseHost:  baseHost,
+		generator: generator,
+		normalize: DefaultNormalizeOptions(),
+		logger:    logging.OrDefault(logger).With("component", "service"),
+	}
+}
+
+// SetNormalizeOptions changes how long URLs are canonicalized
+func (s *URLService) SetNormalizeOptions(opts NormalizeOptions) {
+	s.normalize = opts
+}
+
+// SetDomains allows short URLs on hosts besides the base URL's. Links
+// shortened with a domain are only redirected on the domains they were
+// shortened for; links shortened without one are served on every host.
+func (s *URLService) SetDomains(hosts []string) error {
+	domains := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		domain := tenant.NormalizeHost(host)
+		if domain == "" || strings.ContainsAny(domain, ",/") {
+			return fmt.Errorf("%w: %q", ErrInvalidDomain, host)
+		}
+		domains[domain] = true
+	}
+	s.domains = domains
+	return nil
+}
+
+// Domains returns the allowed short domains, the base URL's host first
+func (s *URLService) Domains() []string {
+	domains := []string{s.baseHost}
+	for domain := range s.domains {
+		if domain != s.baseHost {
+			domains = append(domains, domain)
+		}
 	}
+	slices.Sort(domains[1:])
+	return domains
+}
+
+// ShortenOptions carries the optional parts of a shorten request
+type ShortenOptions struct {
+	Alias     string    // publish under this alias instead of the hash-derived code
+	ExpiresAt time.Time // zero means the link never expires
+	Domain    string    // publish only on this allowed host, empty for every host
+	// RedirectStatus is 301, 302, 307 or 308, zero for the server default.
+	// On an existing link zero keeps the link's status.
+	RedirectStatus int
 }
 
 // idempotent receiver method - same long URL always returns same short URL
-func (s *URLService) ShortenURL(longURL string) (string, string, error) {
+func (s *URLService) ShortenURL(ctx context.Context, longURL string) (string, string, error) {
+	return s.Shorten(ctx, longURL, ShortenOptions{})
+}
+
+// ShortenURLWithAlias publishes longURL under a caller-chosen alias
+func (s *URLService) ShortenURLWithAlias(ctx context.Context, longURL, alias string) (string, string, error) {
+	return s.Shorten(ctx, longURL, ShortenOptions{Alias: alias})
+}
+
+// ShortenResult is a short URL as returned by ShortenLink
+type ShortenResult struct {
+	ShortURL  string
+	ShortCode string
+	Created   bool // false when the URL or alias was already shortened
+
+	// RedirectStatus is the link's own redirect status as stored, 0 for the
+	// server default
+	RedirectStatus int
+	// ExpiresAt is the link's expiry as stored, zero when it never expires
+	ExpiresAt time.Time
+}
+
+// Shorten creates (or returns the existing) short URL for longURL. The URL
+// is canonicalized first, so equivalent spellings share a code. An expiry on
+// a request for an existing link moves that link's expiry.
+func (s *URLService) Shorten(ctx context.Context, longURL string, opts ShortenOptions) (string, string, error) {
+	result, err := s.ShortenLink(ctx, longURL, opts)
+	return result.ShortURL, result.ShortCode, err
+}
+
+// ShortenLink is Shorten, also reporting whether a new link was stored and
+// the redirect status it keeps
+func (s *URLService) ShortenLink(ctx context.Context, longURL string, opts ShortenOptions) (ShortenResult, error) {
+	longURL, err := s.canonicalize(longURL)
+	if err != nil {
+		s.logger.Debug("Shorten invalid URL", logging.URL("url", longURL))
+		return ShortenResult{}, err
+	}
+
+	if !opts.ExpiresAt.IsZero() && !opts.ExpiresAt.After(time.Now()) {
+		s.logger.Debug("Shorten expiry in the past", "expires_at", opts.ExpiresAt)
+		return ShortenResult{}, ErrInvalidExpiry
+	}
 
-	if err := s.validateURL(longURL); err != nil {
-		log.Printf("service: ShortenURL - invalid URL=%s", longURL)
-		return "", "", err
+	if opts.RedirectStatus != 0 && !storage.ValidRedirectStatus(opts.RedirectStatus) {
+		s.logger.Debug("Shorten invalid redirect type", "redirect_type", opts.RedirectStatus)
+		return ShortenResult{}, ErrInvalidRedirect
 	}
 
+	if opts.Domain != "" {
+		domain := tenant.NormalizeHost(opts.Domain)
+		if domain != s.baseHost && !s.domains[domain] {
+			s.logger.Debug("Shorten domain not allowed", "domain", opts.Domain)
+			return ShortenResult{}, ErrInvalidDomain
+		}
+		opts.Domain = domain
+	}
+
+	if opts.Alias != "" {
+		return s.shortenAlias(ctx, longURL, opts)
+	}
+	return s.shortenHash(ctx, longURL, opts)
+}
+
+// shortenHash - hash-derived code with idempotency and collision handling
+func (s *URLService) shortenHash(ctx context.Context, longURL string, opts ShortenOptions) (ShortenResult, error) {
 	// idempotency check - return existing short code if present
-	if shortCode, err := s.storage.GetShortCode(longURL); err == nil {
-		shortURL := fmt.Sprintf("%s/%s", s.baseURL, shortCode)
-		log.Printf("service: ShortenURL - existing mapping found longURL=%s shortCode=%s", longURL, shortCode)
-		return shortURL, shortCode, nil
+	shortCode, err := s.storage.GetShortCode(ctx, longURL)
+	if err == nil {
+		link, err := s.storage.GetLink(ctx, shortCode)
+		if err != nil {
+			s.logger.Error("Shorten existing link lookup failed", "short_code", shortCode, "err", err)
+			return ShortenResult{}, err
+		}
+
+		domains := s.addDomain(link.Domains, opts.Domain)
+		redirectChanged := opts.RedirectStatus != 0 && opts.RedirectStatus != link.RedirectStatus
+		if !opts.ExpiresAt.IsZero() || !slices.Equal(domains, link.Domains) || redirectChanged {
+			if !opts.ExpiresAt.IsZero() {
+				link.ExpiresAt = opts.ExpiresAt
+			}
+			if redirectChanged {
+				link.RedirectStatus = opts.RedirectStatus
+			}
+			link.Domains = domains
+			if err := s.storage.Save(ctx, link); err != nil {
+				s.logger.Error("Shorten failed to update link", "short_code", shortCode, "err", err)
+				return ShortenResult{}, err
+			}
+		}
+		s.logger.Debug("Shorten existing mapping found", logging.URL("long_url", longURL), "short_code", shortCode)
+		return ShortenResult{ShortURL: s.ShortURLOn(opts.Domain, shortCode), ShortCode: shortCode, RedirectStatus: link.RedirectStatus, ExpiresAt: link.ExpiresAt}, nil
+	}
+	if err != storage.ErrNotFound {
+		s.logger.Error("Shorten reverse lookup failed", logging.URL("long_url", longURL), "err", err)
+		return ShortenResult{}, err
+	}
+
+	// collision handling - re-derive until a code is free or already ours
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		shortCode, err := s.generator.Generate(longURL, attempt)
+		if err != nil {
+			s.logger.Error("Shorten code generation failed", logging.URL("long_url", longURL), "err", err)
+			return ShortenResult{}, err
+		}
+		s.logger.Debug("Shorten generated code", "short_code", shortCode, logging.URL("long_url", longURL), "attempt", attempt)
+
+		link := storage.Link{ShortCode: shortCode, LongURL: longURL, ExpiresAt: opts.ExpiresAt, Domains: newDomains(opts.Domain), RedirectStatus: opts.RedirectStatus}
+		err = s.storage.Save(ctx, link)
+		if err == storage.ErrAlreadyExists {
+			s.logger.Debug("Shorten collision retrying", "short_code", shortCode, logging.URL("long_url", longURL))
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Shorten failed to save mapping", "short_code", shortCode, logging.URL("long_url", longURL), "err", err)
+			return ShortenResult{}, err
+		}
+
+		shortURL := s.ShortURLOn(opts.Domain, shortCode)
+		s.logger.Info("Shorten saved mapping", "short_code", shortCode, logging.URL("short_url", shortURL))
+		return ShortenResult{ShortURL: shortURL, ShortCode: shortCode, Created: true, RedirectStatus: opts.RedirectStatus, ExpiresAt: opts.ExpiresAt}, nil
+	}
+
+	s.logger.Debug("Shorten no free short code", logging.URL("long_url", longURL), "max_code_attempts", maxCodeAttempts)
+	return ShortenResult{}, ErrCodeSpaceExhausted
+}
+
+// shortenAlias publishes longURL under opts.Alias. Repeating the same
+// alias/URL pair is idempotent; an alias owned by another URL is
+// ErrAliasTaken. The hash-derived code for longURL is left untouched.
+func (s *URLService) shortenAlias(ctx context.Context, longURL string, opts ShortenOptions) (ShortenResult, error) {
+	alias := opts.Alias
+	if err := ValidateAlias(alias); err != nil {
+		s.logger.Debug("Shorten invalid alias", "alias", alias)
+		return ShortenResult{}, err
+	}
+
+	created, domains, redirectStatus, expiresAt := true, newDomains(opts.Domain), opts.RedirectStatus, opts.ExpiresAt
+	if s.storage.Exists(ctx, alias) {
+		existing, err := s.storage.GetLink(ctx, alias)
+		if err == nil && existing.LongURL != longURL {
+			s.logger.Debug("Shorten alias taken", "alias", alias, logging.URL("existing", existing.LongURL))
+			return ShortenResult{}, ErrAliasTaken
+		}
+		if err == nil {
+			created, domains = false, s.addDomain(existing.Domains, opts.Domain)
+			if redirectStatus == 0 {
+				redirectStatus = existing.RedirectStatus
+			}
+			if expiresAt.IsZero() {
+				expiresAt = existing.ExpiresAt
+			}
+		}
 	}
 
-	shortCode := s.GenerateShortCode(longURL)
-	log.Printf("service: ShortenURL - generated shortCode=%s for longURL=%s", shortCode, longURL)
+	link := storage.Link{ShortCode: alias, LongURL: longURL, ExpiresAt: expiresAt, Domains: domains, RedirectStatus: redirectStatus}
+	if err := s.storage.SaveAlias(ctx, link); err != nil {
+		if err == storage.ErrAlreadyExists {
+			s.logger.Debug("Shorten alias taken", "alias", alias)
+			return ShortenResult{}, ErrAliasTaken
+		}
+		s.logger.Error("Shorten failed to save alias", "alias", alias, logging.URL("long_url", longURL), "err", err)
+		return ShortenResult{}, err
+	}
+
+	shortURL := s.ShortURLOn(opts.Domain, alias)
+	s.logger.Info("Shorten saved alias", "alias", alias, logging.URL("short_url", shortURL))
+	return ShortenResult{ShortURL: shortURL, ShortCode: alias, Created: created, RedirectStatus: redirectStatus, ExpiresAt: expiresAt}, nil
+}
+
+// newDomains is the domains of a link first shortened on domain
+func newDomains(domain string) []string {
+	if domain == "" {
+		return nil
+	}
+	return []string{domain}
+}
 
-	if err := s.storage.Save(shortCode, longURL); err != nil {
-		log.Printf("service: ShortenURL - failed to save mapping shortCode=%s longURL=%s err=%v", shortCode, longURL, err)
-		return "", "", err
+// addDomain is the domains of an existing link shortened again on domain.
+// A link served on every host stays that way; a pinned link gains domain,
+// or the base URL's host for a request without one.
+func (s *URLService) addDomain(domains []string, domain string) []string {
+	if len(domains) == 0 {
+		return domains
 	}
+	if domain == "" {
+		domain = s.baseHost
+	}
+	if slices.Contains(domains, domain) {
+		return domains
+	}
+	return append(slices.Clone(domains), domain)
+}
 
-	shortURL := fmt.Sprintf("%s/%s", s.baseURL, shortCode)
-	log.Printf("service: ShortenURL - saved mapping shortCode=%s shortURL=%s", shortCode, shortURL)
-	return shortURL, shortCode, nil
+// ValidateAlias checks an alias against the charset, length bounds and
+// reserved route names
+func ValidateAlias(alias string) error {
+	if !storage.ValidCode(alias) {
+		return ErrInvalidAlias
+	}
+	return nil
 }
 
 // get long URL by short code
-func (s *URLService) GetLongURL(shortCode string) (string, error) {
-	longURL, err := s.storage.GetLongURL(shortCode)
+func (s *URLService) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	longURL, err := s.storage.GetLongURL(ctx, shortCode)
 	if err != nil {
-		log.Printf("service: GetLongURL - not found shortCode=%s err=%v", shortCode, err)
+		s.logger.Debug("GetLongURL not found", "short_code", shortCode, "err", err)
 		return "", err
 	}
-	log.Printf("service: GetLongURL - found shortCode=%s longURL=%s", shortCode, longURL)
+	s.logger.Debug("GetLongURL found", "short_code", shortCode, logging.URL("long_url", longURL))
 	return longURL, nil
 }
 
-// URL validation
-func (s *URLService) validateURL(urlStr string) error {
-	if urlStr == "" {
-		return ErrInvalidURL
+// Resolve returns the link a redirect for shortCode on host should follow.
+// A link not published on host is storage.ErrNotFound, as if it did not exist.
+func (s *URLService) Resolve(ctx context.Context, host, shortCode string) (storage.Link, error) {
+	link, err := storage.Resolve(ctx, s.storage, shortCode)
+	if err != nil {
+		s.logger.Debug("Resolve not found", "short_code", shortCode, "err", err)
+		return storage.Link{}, err
+	}
+	if host = tenant.NormalizeHost(host); !link.PublishedOn(host) {
+		s.logger.Debug("Resolve not published on host", "short_code", shortCode, "host", host)
+		return storage.Link{}, storage.ErrNotFound
 	}
+	s.logger.Debug("Resolve found", "short_code", shortCode, logging.URL("long_url", link.LongURL))
+	return link, nil
+}
 
-	parsedURL, err := url.ParseRequestURI(urlStr)
+// get the stored link (including expired ones) by short code
+func (s *URLService) GetLink(ctx context.Context, shortCode string) (storage.Link, error) {
+	link, err := s.storage.GetLink(ctx, shortCode)
 	if err != nil {
-		return ErrInvalidURL
+		s.logger.Debug("GetLink not found", "short_code", shortCode, "err", err)
+		return storage.Link{}, err
+	}
+	return link, nil
+}
+
+// UpdateLink points an existing short code at a new long URL
+func (s *URLService) UpdateLink(ctx context.Context, shortCode, longURL string) (storage.Link, error) {
+	longURL, err := s.canonicalize(longURL)
+	if err != nil {
+		s.logger.Debug("UpdateLink invalid URL", logging.URL("url", longURL))
+		return storage.Link{}, err
 	}
 
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return ErrInvalidURL
+	if err := s.storage.Update(ctx, shortCode, longURL); err != nil {
+		s.logger.Debug("UpdateLink failed", "short_code", shortCode, "err", err)
+		return storage.Link{}, err
 	}
 
+	s.logger.Info("UpdateLink retargeted", "short_code", shortCode, logging.URL("long_url", longURL))
+	return s.storage.GetLink(ctx, shortCode)
+}
+
+// DeleteLink removes a short code
+func (s *URLService) DeleteLink(ctx context.Context, shortCode string) error {
+	if err := s.storage.Delete(ctx, shortCode); err != nil {
+		s.logger.Debug("DeleteLink failed", "short_code", shortCode, "err", err)
+		return err
+	}
+	s.logger.Info("DeleteLink deleted", "short_code", shortCode)
 	return nil
 }
 
-// code generation using SHA-256 and base64 encoding
-func (s *URLService) GenerateShortCode(longURL string) string {
-	log.Printf("service: GenerateShortCode - generating for URL=%s", longURL)
-	hash := sha256.Sum256([]byte(longURL))
-	encoded := base64.URLEncoding.EncodeToString(hash[:])
+// ListLinks pages through stored links ordered by creation time
+func (s *URLService) ListLinks(ctx context.Context, opts storage.ListOptions) (storage.ListPage, error) {
+	return s.storage.List(ctx, opts)
+}
+
+// ExportLinks writes every stored link to w as JSON Lines
+func (s *URLService) ExportLinks(ctx context.Context, w io.Writer) (int, error) {
+	return storage.Export(ctx, s.storage, w)
+}
+
+// ImportLinks replays an export from r into storage
+func (s *URLService) ImportLinks(ctx context.Context, r io.Reader, opts storage.ImportOptions) (storage.ImportReport, error) {
+	return storage.Import(ctx, s.storage, r, opts)
+}
 
-	shortCode := strings.TrimRight(encoded, "=")[:8]
+// Ping checks that storage can serve requests
+func (s *URLService) Ping(ctx context.Context) error {
+	return storage.Ping(ctx, s.storage)
+}
+
+// ShortURL builds the public URL for a short code
+func (s *URLService) ShortURL(shortCode string) string {
+	return fmt.Sprintf("%s/%s", s.baseURL, shortCode)
+}
+
+// ShortURLOn builds the public URL for a short code on domain, keeping the
+// base URL's scheme. An empty domain or the base URL's host is ShortURL.
+func (s *URLService) ShortURLOn(domain, shortCode string) string {
+	if domain == "" || domain == s.baseHost {
+		return s.ShortURL(shortCode)
+	}
+	scheme, _, _ := strings.Cut(s.baseURL, "://")
+	return fmt.Sprintf("%s://%s/%s", scheme, domain, shortCode)
+}
+
+// LinkURL is the public URL of link: on its first domain when pinned
+func (s *URLService) LinkURL(link storage.Link) string {
+	if len(link.Domains) > 0 {
+		return s.ShortURLOn(link.Domains[0], link.ShortCode)
+	}
+	return s.ShortURL(link.ShortCode)
+}
+
+// canonicalize validates urlStr and returns its normalized form
+func (s *URLService) canonicalize(urlStr string) (string, error) {
+	if err := s.validateURL(urlStr); err != nil {
+		return urlStr, err
+	}
+
+	canonical, err := NormalizeURL(urlStr, s.normalize)
+	if err != nil {
+		return urlStr, err
+	}
+	if canonical != urlStr {
+		s.logger.Debug("canonicalize rewrote URL", logging.URL("url", urlStr), logging.URL("canonical", canonical))
+	}
+	return canonical, nil
+}
 
+// URL validation
+func (s *URLService) validateURL(urlStr string) error {
+	if !storage.ValidLongURL(urlStr) {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// first candidate code the configured generator yields for longURL
+func (s *URLService) GenerateShortCode(longURL string) string {
+	s.logger.Debug("GenerateShortCode generating", logging.URL("url", longURL))
+	shortCode, err := s.generator.Generate(longURL, 0)
+	if err != nil {
+		s.logger.Error("GenerateShortCode failed", logging.URL("url", longURL), "err", err)
+	}
 	return shortCode
 }
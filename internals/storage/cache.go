@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheOptions configures CachedStorage
+type CacheOptions struct {
+	Size        int           // entries kept before the least recently used is evicted
+	TTL         time.Duration // how long a cached hit is trusted, 0 until evicted or invalidated
+	NegativeTTL time.Duration // how long an unknown code is remembered, 0 disables negative caching
+}
+
+// DefaultCacheOptions returns the options used when none are given
+func DefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		Size:        10000,
+		NegativeTTL: 5 * time.Second,
+	}
+}
+
+// CacheStats counts CachedStorage lookups
+type CacheStats struct {
+	Hits      uint64 // GetLongURL answered from the cache, negative hits included
+	Misses    uint64 // GetLongURL that went to the wrapped storage
+	Evictions uint64 // entries dropped to stay within Size
+	Entries   int    // entries currently cached
+}
+
+// cacheEntry is one cached Resolve answer. A negative entry remembers
+// that the code was unknown.
+type cacheEntry struct {
+	shortCode string
+	link      Link // LongURL, ExpiresAt, Domains and RedirectStatus of the link
+	negative  bool
+	staleAt   time.Time // when the entry stops being trusted, zero for never
+}
+
+// CachedStorage is a read-through LRU cache in front of another Storage.
+// Only GetLongURL and Resolve, the redirect path, are cached; every write to a code
+// through the cache drops that code's entry. Writes made to the wrapped
+// storage directly, or by other replicas, are only seen once the entry
+// goes stale, so set TTL when the storage is shared.
+type CachedStorage struct {
+	next Storage
+	opts CacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used
+	// bumped on every invalidation so a lookup racing a write never caches
+	// the value read before the write
+	generation uint64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// NewCachedStorage wraps next with a cache of opts.Size entries
+func NewCachedStorage(next Storage, opts CacheOptions) *CachedStorage {
+	if opts.Size < 1 {
+		opts.Size = DefaultCacheOptions().Size
+	}
+	return &CachedStorage{
+		next:    next,
+		opts:    opts,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Stats returns the hit/miss counters and current size
+func (c *CachedStorage) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// save through to the wrapped storage, dropping any cached entry for the code
+func (c *CachedStorage) Save(ctx context.Context, link Link) error {
+	defer c.invalidate(link.ShortCode)
+	return c.next.Save(ctx, link)
+}
+
+// save an alias through to the wrapped storage, dropping any cached entry
+func (c *CachedStorage) SaveAlias(ctx context.Context, link Link) error {
+	defer c.invalidate(link.ShortCode)
+	return c.next.SaveAlias(ctx, link)
+}
+
+// retarget through to the wrapped storage, dropping any cached entry
+func (c *CachedStorage) Update(ctx context.Context, shortCode, longURL string) error {
+	defer c.invalidate(shortCode)
+	return c.next.Update(ctx, shortCode, longURL)
+}
+
+// delete through to the wrapped storage, dropping any cached entry
+func (c *CachedStorage) Delete(ctx context.Context, shortCode string) error {
+	defer c.invalidate(shortCode)
+	return c.next.Delete(ctx, shortCode)
+}
+
+// retrieve the full link from the wrapped storage, uncached
+func (c *CachedStorage) GetLink(ctx context.Context, shortCode string) (Link, error) {
+	return c.next.GetLink(ctx, shortCode)
+}
+
+// retrieve longURL by shortCode, from the cache when possible
+func (c *CachedStorage) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	link, err := c.Resolve(ctx, shortCode)
+	return link.LongURL, err
+}
+
+// Resolve implements Resolver from the cache when possible. Misses read the
+// full link so the cached entry knows when the link expires, where it is
+// published and how it redirects.
+func (c *CachedStorage) Resolve(ctx context.Context, shortCode string) (Link, error) {
+	if err := ctx.Err(); err != nil {
+		return Link{}, err
+	}
+
+	now := time.Now()
+	if entry, ok := c.lookup(shortCode, now); ok {
+		c.hits.Add(1)
+		switch {
+		case entry.negative:
+			return Link{}, ErrNotFound
+		case entry.link.Expired(now):
+			return Link{}, ErrExpired
+		}
+		return entry.link, nil
+	}
+	c.misses.Add(1)
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	link, err := c.next.GetLink(ctx, shortCode)
+	switch {
+	case err == ErrNotFound:
+		if c.opts.NegativeTTL > 0 {
+			c.store(generation, &cacheEntry{shortCode: shortCode, negative: true, staleAt: now.Add(c.opts.NegativeTTL)})
+		}
+		return Link{}, ErrNotFound
+	case err != nil:
+		return Link{}, err
+	case link.Expired(now):
+		// not cached, so the janitor reclaiming it is seen straight away
+		return Link{}, ErrExpired
+	}
+
+	link = Link{ShortCode: link.ShortCode, LongURL: link.LongURL, ExpiresAt: link.ExpiresAt, Domains: link.Domains, RedirectStatus: link.RedirectStatus}
+	entry := &cacheEntry{shortCode: shortCode, link: link}
+	if c.opts.TTL > 0 {
+		entry.staleAt = now.Add(c.opts.TTL)
+	}
+	c.store(generation, entry)
+	return link, nil
+}
+
+// retrieve shortCode by longURL from the wrapped storage, uncached
+func (c *CachedStorage) GetShortCode(ctx context.Context, longURL string) (string, error) {
+	return c.next.GetShortCode(ctx, longURL)
+}
+
+// check if shortCode exists in the wrapped storage, uncached
+func (c *CachedStorage) Exists(ctx context.Context, shortCode string) bool {
+	return c.next.Exists(ctx, shortCode)
+}
+
+// page through links in the wrapped storage, uncached
+func (c *CachedStorage) List(ctx context.Context, opts ListOptions) (ListPage, error) {
+	return c.next.List(ctx, opts)
+}
+
+// check the wrapped storage can serve
+func (c *CachedStorage) Ping(ctx context.Context) error {
+	return Ping(ctx, c.next)
+}
+
+// lookup returns the live entry for shortCode and marks it recently used,
+// dropping it instead if it has gone stale
+func (c *CachedStorage) lookup(shortCode string, now time.Time) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[shortCode]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !entry.staleAt.IsZero() && !now.Before(entry.staleAt) {
+		c.remove(elem)
+		return cacheEntry{}, false
+	}
+	c.lru.MoveToFront(elem)
+	return *entry, true
+}
+
+// store caches entry unless a write invalidated anything since generation
+// was read, evicting the least recently used entries beyond Size
+func (c *CachedStorage) store(generation uint64, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if elem, ok := c.entries[entry.shortCode]; ok {
+		c.remove(elem)
+	}
+	c.entries[entry.shortCode] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.opts.Size {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// invalidate drops the entry for shortCode
+func (c *CachedStorage) invalidate(shortCode string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.entries[shortCode]; ok {
+		c.remove(elem)
+	}
+}
+
+// remove unlinks elem from the list and the index. Caller must hold c.mu.
+func (c *CachedStorage) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).shortCode)
+}
@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+var (
+	ErrImportConflict = errors.New("import conflicts with stored links")
+	ErrInvalidRecord  = errors.New("invalid export record")
+)
+
+// ConflictPolicy decides what Import does with a record whose code is
+// already stored with different contents
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"      // keep the stored link
+	ConflictOverwrite ConflictPolicy = "overwrite" // replace it with the record
+	ConflictFail      ConflictPolicy = "fail"      // write nothing if any record conflicts
+)
+
+// ParseConflictPolicy converts an env/flag/query value into a ConflictPolicy
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return p, nil
+	case "":
+		return ConflictSkip, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q", s)
+}
+
+// longest line accepted by Import
+const maxExportLine = 1 << 20
+
+// conflicts listed individually in an ImportReport; the rest are only counted
+const maxReportedConflicts = 100
+
+// ExportRecord is one line of an export: a link and its metadata
+type ExportRecord struct {
+	Code      string     `json:"code"`
+	LongURL   string     `json:"long_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Domains   []string   `json:"domains,omitempty"`
+	Redirect  int        `json:"redirect_type,omitempty"` // 301, 302, 307 or 308, absent for the server default
+	// Alias marks a code that is not the live reverse lookup for its URL,
+	// so importing it leaves the URL's own code alone
+	Alias bool `json:"alias,omitempty"`
+
+	line int // position in the import, for reporting
+}
+
+// link converts a record into the Link it describes
+func (r ExportRecord) link() Link {
+	link := Link{ShortCode: r.Code, LongURL: r.LongURL, CreatedAt: r.CreatedAt, Domains: r.Domains, RedirectStatus: r.Redirect}
+	if r.ExpiresAt != nil {
+		link.ExpiresAt = *r.ExpiresAt
+	}
+	return link
+}
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	Policy ConflictPolicy
+	DryRun bool // report what would happen without writing
+}
+
+// ImportConflict is a record whose code is stored with different contents
+type ImportConflict struct {
+	Line     int
+	Code     string
+	Existing string // stored long URL
+	Incoming string // long URL in the record
+}
+
+// ImportReport counts what Import did, or would do on a dry run
+type ImportReport struct {
+	DryRun      bool
+	Records     int
+	Created     int              // codes that were not stored yet
+	Unchanged   int              // codes already stored exactly as in the record
+	Conflicted  int              // codes stored with different contents
+	Skipped     int              // conflicts left alone under ConflictSkip
+	Overwritten int              // conflicts replaced under ConflictOverwrite
+	Conflicts   []ImportConflict // the first conflicts, in input order
+}
+
+// Export writes every link in store to w as JSON Lines, oldest first, and
+// returns how many were written. Links written while the export runs may
+// or may not be included.
+func Export(ctx context.Context, store Storage, w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	opts := ListOptions{Limit: MaxListLimit}
+	written := 0
+	for {
+		page, err := store.List(ctx, opts)
+		if err != nil {
+			return written, err
+		}
+
+		for _, link := range page.Links {
+			rec := ExportRecord{Code: link.ShortCode, LongURL: link.LongURL, CreatedAt: link.CreatedAt, Domains: link.Domains, Redirect: link.RedirectStatus}
+			if !link.ExpiresAt.IsZero() {
+				expiresAt := link.ExpiresAt
+				rec.ExpiresAt = &expiresAt
+			}
+			code, err := store.GetShortCode(ctx, link.LongURL)
+			if err != nil && err != ErrNotFound {
+				return written, err
+			}
+			rec.Alias = code != link.ShortCode
+
+			if err := enc.Encode(rec); err != nil {
+				return written, err
+			}
+			written++
+		}
+
+		if page.NextCursor == "" {
+			return written, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// Import replays an export from r into store through Save and SaveAlias.
+// Every record is read and checked against store before anything is
+// written, so a malformed line, or any conflict under ConflictFail, leaves
+// store untouched. The report says what was, or on a dry run would be, done.
+func Import(ctx context.Context, store Storage, r io.Reader, opts ImportOptions) (ImportReport, error) {
+	report := ImportReport{DryRun: opts.DryRun}
+	if opts.Policy == "" {
+		opts.Policy = ConflictSkip
+	}
+
+	records, err := readExport(r)
+	if err != nil {
+		return report, err
+	}
+	report.Records = len(records)
+
+	// plan every record before writing any of them
+	type step struct {
+		rec       ExportRecord
+		overwrite bool
+	}
+	var plan []step
+	for _, rec := range records {
+		existing, err := store.GetLink(ctx, rec.Code)
+		switch {
+		case err == ErrNotFound:
+			report.Created++
+			plan = append(plan, step{rec: rec})
+			continue
+		case err != nil:
+			return report, err
+		case sameLink(existing, rec.link()):
+			report.Unchanged++
+			continue
+		}
+
+		report.Conflicted++
+		if len(report.Conflicts) < maxReportedConflicts {
+			report.Conflicts = append(report.Conflicts, ImportConflict{Line: rec.line, Code: rec.Code, Existing: existing.LongURL, Incoming: rec.LongURL})
+		}
+		switch opts.Policy {
+		case ConflictOverwrite:
+			report.Overwritten++
+			plan = append(plan, step{rec: rec, overwrite: true})
+		case ConflictSkip:
+			report.Skipped++
+		}
+	}
+
+	if opts.Policy == ConflictFail && report.Conflicted > 0 {
+		return report, ErrImportConflict
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, s := range plan {
+		if s.overwrite {
+			if err := store.Delete(ctx, s.rec.Code); err != nil && err != ErrNotFound {
+				return report, err
+			}
+		}
+
+		save := store.Save
+		if s.rec.Alias {
+			save = store.SaveAlias
+		}
+		if err := save(ctx, s.rec.link()); err != nil {
+			return report, fmt.Errorf("import code %s: %w", s.rec.Code, err)
+		}
+	}
+
+	return report, nil
+}
+
+// readExport parses and validates every line of an export
+func readExport(r io.Reader) ([]ExportRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxExportLine)
+
+	var records []ExportRecord
+	seen := make(map[string]int)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var rec ExportRecord
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			if readErr := scanner.Err(); readErr != nil {
+				// the line was cut short by the reader failing, not malformed
+				return nil, fmt.Errorf("%w after line %d: %w", ErrInvalidRecord, line-1, readErr)
+			}
+			return nil, fmt.Errorf("%w at line %d: %v", ErrInvalidRecord, line, err)
+		}
+		if rec.Code == "" || rec.LongURL == "" {
+			return nil, fmt.Errorf("%w at line %d: code and long_url are required", ErrInvalidRecord, line)
+		}
+		if !ValidCode(rec.Code) {
+			return nil, fmt.Errorf("%w at line %d: code %q must be %d-%d characters of letters, digits, '-' or '_' and not a reserved name", ErrInvalidRecord, line, rec.Code, MinCodeLength, MaxCodeLength)
+		}
+		if !ValidLongURL(rec.LongURL) {
+			return nil, fmt.Errorf("%w at line %d: long_url must be an absolute http or https URL", ErrInvalidRecord, line)
+		}
+		if rec.Redirect != 0 && !ValidRedirectStatus(rec.Redirect) {
+			return nil, fmt.Errorf("%w at line %d: redirect_type %d is not 301, 302, 307 or 308", ErrInvalidRecord, line, rec.Redirect)
+		}
+		if first, dup := seen[rec.Code]; dup {
+			return nil, fmt.Errorf("%w at line %d: code %s already on line %d", ErrInvalidRecord, line, rec.Code, first)
+		}
+		seen[rec.Code] = line
+		rec.line = line
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w after line %d: %w", ErrInvalidRecord, line, err)
+	}
+	return records, nil
+}
+
+// sameLink reports whether a stored link already matches an imported one.
+// A record without a creation time matches any.
+func sameLink(stored, imported Link) bool {
+	if imported.CreatedAt.IsZero() {
+		imported.CreatedAt = stored.CreatedAt
+	}
+	return stored.LongURL == imported.LongURL && stored.CreatedAt.Equal(imported.CreatedAt) && stored.ExpiresAt.Equal(imported.ExpiresAt) &&
+		slices.Equal(stored.Domains, imported.Domains) && stored.RedirectStatus == imported.RedirectStatus
+}
@@ -0,0 +1,633 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/logging"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+	lockFileName     = "lock"
+
+	// record header: 4 byte payload length + 4 byte CRC32 of the payload
+	recordHeaderSize = 8
+	maxRecordSize    = 1 << 20
+)
+
+// SyncPolicy controls when the write-ahead log is fsynced
+type SyncPolicy string
+
+const (
+	SyncAlways   SyncPolicy = "always"   // fsync after every record
+	SyncInterval SyncPolicy = "interval" // fsync in the background every SyncInterval
+	SyncNever    SyncPolicy = "never"    // leave flushing to the OS
+)
+
+// ParseSyncPolicy converts an env/config value into a SyncPolicy
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case SyncAlways, SyncInterval, SyncNever:
+		return p, nil
+	case "":
+		return SyncAlways, nil
+	}
+	return "", fmt.Errorf("unknown fsync policy %q", s)
+}
+
+// LogFile is the part of *os.File the write-ahead log needs
+type LogFile interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// FileOptions configures FileStorage
+type FileOptions struct {
+	Sync          SyncPolicy
+	SyncInterval  time.Duration
+	SnapshotEvery int          // log records written between snapshots, 0 disables snapshots
+	Logger        *slog.Logger // nil uses slog.Default()
+	// OpenLog opens the write-ahead log for appending, nil opens the file
+	// at path. Tests use it to inject a failing disk.
+	OpenLog func(path string) (LogFile, error)
+}
+
+// DefaultFileOptions returns the options used when none are given
+func DefaultFileOptions() FileOptions {
+	return FileOptions{
+		Sync:          SyncAlways,
+		SyncInterval:  time.Second,
+		SnapshotEvery: 1000,
+	}
+}
+
+// FileStorage implements Storage on top of MemoryStorage, persisting every
+// mutation to an append-only log in dir and compacting it into a snapshot
+type FileStorage struct {
+	mem  *MemoryStorage
+	dir  string
+	opts FileOptions
+
+	logger *slog.Logger
+
+	lock *os.File // exclusive lock on dir, see lockDir
+
+	mu      sync.Mutex
+	wal     LogFile
+	size    int64 // bytes of whole records in the log
+	records int
+	dirty   bool
+	closed  bool
+	failed  error // set when a failed append could not be undone
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// walRecord is the payload of a single log record
+type walRecord struct {
+	Op        string    `json:"op"`
+	ShortCode string    `json:"code"`
+	LongURL   string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Domains   []string  `json:"domains,omitempty"`
+	Redirect  int       `json:"redirect,omitempty"`
+}
+
+// newWalRecord builds the log record for op on link
+func newWalRecord(op string, link Link) walRecord {
+	return walRecord{
+		Op:        op,
+		ShortCode: link.ShortCode,
+		LongURL:   link.LongURL,
+		CreatedAt: link.CreatedAt,
+		ExpiresAt: link.ExpiresAt,
+		Domains:   link.Domains,
+		Redirect:  link.RedirectStatus,
+	}
+}
+
+// link converts a log record back into a Link
+func (r walRecord) link() Link {
+	return Link{
+		ShortCode:      r.ShortCode,
+		LongURL:        r.LongURL,
+		CreatedAt:      r.CreatedAt,
+		ExpiresAt:      r.ExpiresAt,
+		Domains:        r.Domains,
+		RedirectStatus: r.Redirect,
+	}
+}
+
+// snapshot is the on-disk format of a compacted log
+type snapshot struct {
+	Entries []walRecord `json:"entries"`
+}
+
+// NewFileStorage opens (or creates) a file-backed storage in dir and
+// replays the snapshot and write-ahead log found there. Only one process may
+// hold dir at a time; another gets ErrLocked.
+func NewFileStorage(dir string, opts FileOptions) (*FileStorage, error) {
+	if opts.Sync == "" {
+		opts.Sync = SyncAlways
+	}
+	if opts.Sync == SyncInterval && opts.SyncInterval <= 0 {
+		opts.SyncInterval = time.Second
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("storage: create dir: %w", err)
+	}
+
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FileStorage{
+		mem:    NewMemoryStorage(opts.Logger),
+		dir:    dir,
+		opts:   opts,
+		logger: logging.OrDefault(opts.Logger).With("component", "storage"),
+		lock:   lock,
+	}
+
+	if err := f.loadSnapshot(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	if err := f.replayLog(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	openLog := opts.OpenLog
+	if openLog == nil {
+		openLog = openLogFile
+	}
+	wal, err := openLog(filepath.Join(dir, walFileName))
+	if err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("storage: open wal: %w", err)
+	}
+	f.wal = wal
+
+	if opts.Sync == SyncInterval {
+		f.stop = make(chan struct{})
+		f.done = make(chan struct{})
+		go f.syncLoop()
+	}
+
+	f.logger.Info("NewFileStorage opened", "dir", dir, "fsync", opts.Sync)
+	return f, nil
+}
+
+// openLogFile opens the log at path for appending
+func openLogFile(path string) (LogFile, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// map of shortCode to longURL, logged before it is applied
+func (f *FileStorage) Save(ctx context.Context, link Link) error {
+	return f.write(ctx, "save", link)
+}
+
+// map of alias to longURL, logged before it is applied
+func (f *FileStorage) SaveAlias(ctx context.Context, link Link) error {
+	return f.write(ctx, "alias", link)
+}
+
+// point shortCode at longURL, logged before it is applied
+func (f *FileStorage) Update(ctx context.Context, shortCode, longURL string) error {
+	return f.write(ctx, "update", Link{ShortCode: shortCode, LongURL: longURL})
+}
+
+// remove shortCode, logged before it is applied
+func (f *FileStorage) Delete(ctx context.Context, shortCode string) error {
+	return f.write(ctx, "delete", Link{ShortCode: shortCode})
+}
+
+// retrieve the full link by shortCode
+func (f *FileStorage) GetLink(ctx context.Context, shortCode string) (Link, error) {
+	return f.mem.GetLink(ctx, shortCode)
+}
+
+// retrieve longURL by shortCode
+func (f *FileStorage) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	return f.mem.GetLongURL(ctx, shortCode)
+}
+
+// retrieve shortCode by longURL
+func (f *FileStorage) GetShortCode(ctx context.Context, longURL string) (string, error) {
+	return f.mem.GetShortCode(ctx, longURL)
+}
+
+// check if shortCode exists
+func (f *FileStorage) Exists(ctx context.Context, shortCode string) bool {
+	return f.mem.Exists(ctx, shortCode)
+}
+
+// page through links ordered by creation time
+func (f *FileStorage) List(ctx context.Context, opts ListOptions) (ListPage, error) {
+	return f.mem.List(ctx, opts)
+}
+
+// Len returns how many links are held in memory
+func (f *FileStorage) Len() int {
+	return f.mem.Len()
+}
+
+// PurgeExpired reclaims expired links from memory. Nothing is logged: an
+// expired record replayed later is purged again.
+func (f *FileStorage) PurgeExpired() int {
+	return f.mem.PurgeExpired()
+}
+
+// StartJanitor reclaims expired links every interval until Close is called
+func (f *FileStorage) StartJanitor(interval time.Duration) {
+	f.mem.StartJanitor(interval)
+}
+
+// write appends a mutation to the log and only then applies it in memory, so
+// readers never see a change the log does not hold. Once logged, the record
+// is applied even if ctx ends, so memory and log agree.
+func (f *FileStorage) write(ctx context.Context, op string, link Link) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+	if f.failed != nil {
+		return f.failed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rec, err := f.plan(op, link)
+	if err != nil {
+		return err
+	}
+
+	if err := f.append(rec); err != nil {
+		f.logger.Error("FileStorage wal append failed", "op", op, "short_code", link.ShortCode, "err", err)
+		return err
+	}
+
+	f.apply(rec)
+
+	// compact once memory holds the record, so the snapshot includes it
+	if f.opts.SnapshotEvery > 0 && f.records >= f.opts.SnapshotEvery {
+		if err := f.snapshot(); err != nil {
+			// the log still holds every record, so this is not fatal
+			f.logger.Error("FileStorage snapshot failed", "err", err)
+		}
+	}
+	return nil
+}
+
+// plan checks op against memory without changing it and returns the record
+// to log: the link as memory will hold it, so the creation time survives a
+// replay. Writers are serialized by f.mu, so memory cannot change between
+// plan and apply. Caller must hold f.mu.
+func (f *FileStorage) plan(op string, link Link) (walRecord, error) {
+	f.mem.mu.RLock()
+	defer f.mem.mu.RUnlock()
+
+	now := time.Now()
+	existing, exists := f.mem.shortToLong[link.ShortCode]
+	switch op {
+	case "save", "alias":
+		if exists && existing.LongURL != link.LongURL && !existing.Expired(now) {
+			f.logger.Debug("FileStorage collision", "short_code", link.ShortCode, logging.URL("existing", existing.LongURL), logging.URL("new", link.LongURL))
+			return walRecord{}, ErrAlreadyExists
+		}
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+			if exists && !existing.Expired(now) {
+				link.CreatedAt = existing.CreatedAt
+			}
+		}
+		return newWalRecord(op, link), nil
+	case "update":
+		if !exists {
+			return walRecord{}, ErrNotFound
+		}
+		existing.LongURL = link.LongURL
+		return newWalRecord(op, existing), nil
+	default: // delete
+		if !exists {
+			return walRecord{}, ErrNotFound
+		}
+		return walRecord{Op: op, ShortCode: link.ShortCode}, nil
+	}
+}
+
+// Ping checks the write-ahead log is still open for writes
+func (f *FileStorage) Ping(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+	return f.failed
+}
+
+// Close flushes and closes the write-ahead log, then releases dir
+func (f *FileStorage) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	f.mu.Unlock()
+
+	if f.stop != nil {
+		close(f.stop)
+		<-f.done
+	}
+	f.mem.Close()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer f.lock.Close()
+
+	if err := f.wal.Sync(); err != nil {
+		f.wal.Close()
+		return err
+	}
+	return f.wal.Close()
+}
+
+// append writes a framed record to the log, honouring the fsync policy. A
+// record that fails to write or sync is cut off again, so the log never holds
+// a torn frame ahead of later records, nor a write its caller saw fail. When
+// even that fails, the storage refuses further writes. Caller must hold f.mu.
+func (f *FileStorage) append(rec walRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[recordHeaderSize:], payload)
+
+	if _, err := f.wal.Write(buf); err != nil {
+		return f.undoAppend(err)
+	}
+
+	switch f.opts.Sync {
+	case SyncAlways:
+		if err := f.wal.Sync(); err != nil {
+			return f.undoAppend(err)
+		}
+	case SyncInterval:
+		f.dirty = true
+	}
+
+	f.size += int64(len(buf))
+	f.records++
+	return nil
+}
+
+// undoAppend truncates the log back to its last whole record after a failed
+// append and returns err. Caller must hold f.mu.
+func (f *FileStorage) undoAppend(err error) error {
+	if truncErr := f.wal.Truncate(f.size); truncErr != nil {
+		f.failed = fmt.Errorf("storage: wal left with a partial record: %w", truncErr)
+		f.logger.Error("FileStorage wal truncate failed, refusing writes", "offset", f.size, "err", truncErr)
+	}
+	return err
+}
+
+// snapshot writes the full mapping to a new snapshot file and truncates
+// the log. Caller must hold f.mu.
+func (f *FileStorage) snapshot() error {
+	var snap snapshot
+	f.mem.mu.RLock()
+	now := time.Now()
+	for code, link := range f.mem.shortToLong {
+		if link.Expired(now) {
+			continue
+		}
+		op := "alias"
+		if f.mem.longToShort[link.LongURL] == code {
+			op = "save"
+		}
+		snap.Entries = append(snap.Entries, newWalRecord(op, link))
+	}
+	f.mem.mu.RUnlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(f.dir, snapshotFileName)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	if err := syncDir(f.dir); err != nil {
+		return err
+	}
+
+	// a crash before the truncate only means the log replays on top of an
+	// identical snapshot, which is harmless
+	if err := f.wal.Truncate(0); err != nil {
+		return err
+	}
+	f.size = 0
+	if err := f.wal.Sync(); err != nil {
+		return err
+	}
+
+	f.logger.Debug("FileStorage snapshot written", "entries", len(snap.Entries))
+	f.records = 0
+	f.dirty = false
+	return nil
+}
+
+// loadSnapshot restores the last snapshot, if any
+func (f *FileStorage) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(f.dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("storage: read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("storage: decode snapshot: %w", err)
+	}
+
+	for _, rec := range snap.Entries {
+		f.apply(rec)
+	}
+	f.logger.Debug("FileStorage loaded snapshot", "entries", len(snap.Entries))
+	return nil
+}
+
+// replayLog applies every intact record in the log. A torn or corrupt
+// record means the process died mid-write, so the log is truncated there.
+func (f *FileStorage) replayLog() error {
+	path := filepath.Join(f.dir, walFileName)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("storage: open wal: %w", err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	var offset int64
+	var count int
+	for {
+		rec, n, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.logger.Warn("FileStorage torn record, truncating", "offset", offset, "err", err)
+			if err := os.Truncate(path, offset); err != nil {
+				return fmt.Errorf("storage: truncate wal: %w", err)
+			}
+			break
+		}
+		f.apply(rec)
+		offset += int64(n)
+		count++
+	}
+
+	f.size = offset
+	f.records = count
+	f.logger.Debug("FileStorage replayed wal", "records", count)
+	return nil
+}
+
+// apply replays a single record into memory
+func (f *FileStorage) apply(rec walRecord) {
+	ctx := context.Background()
+	switch rec.Op {
+	case "save":
+		f.mem.Save(ctx, rec.link())
+	case "alias":
+		f.mem.SaveAlias(ctx, rec.link())
+	case "update":
+		f.mem.Update(ctx, rec.ShortCode, rec.LongURL)
+	case "delete":
+		f.mem.Delete(ctx, rec.ShortCode)
+	default:
+		f.logger.Warn("FileStorage skipping unknown op", "op", rec.Op)
+	}
+}
+
+// syncLoop flushes the log in the background for SyncInterval
+func (f *FileStorage) syncLoop() {
+	defer close(f.done)
+	ticker := time.NewTicker(f.opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.mu.Lock()
+			if f.dirty && !f.closed {
+				if err := f.wal.Sync(); err != nil {
+					f.logger.Error("FileStorage background fsync failed", "err", err)
+				} else {
+					f.dirty = false
+				}
+			}
+			f.mu.Unlock()
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// readRecord reads one framed record, returning io.EOF only on a clean end
+func readRecord(r io.Reader) (walRecord, int, error) {
+	var rec walRecord
+
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF {
+			return rec, 0, io.EOF
+		}
+		return rec, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if size == 0 || size > maxRecordSize {
+		return rec, 0, fmt.Errorf("invalid record size %d", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return rec, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return rec, 0, errors.New("checksum mismatch")
+	}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, 0, err
+	}
+
+	return rec, recordHeaderSize + int(size), nil
+}
+
+// writeFileSync writes data to path and fsyncs it before returning
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir fsyncs a directory so a rename inside it is durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 1000
+)
+
+// ListOptions selects a page of links, ordered by creation time
+type ListOptions struct {
+	Cursor string // opaque position returned as ListPage.NextCursor, empty for the first page
+	Limit  int    // page size, DefaultListLimit if <= 0, capped at MaxListLimit
+	Query  string // case-insensitive substring of the short code or long URL
+	Host   string // case-insensitive exact host of the long URL
+}
+
+// ListPage is one page of links and the cursor for the next one
+type ListPage struct {
+	Links      []Link
+	NextCursor string // empty on the last page
+}
+
+// listCursor is the position after which the next page starts. Creation
+// time alone is not unique, so the short code breaks ties.
+type listCursor struct {
+	createdAt time.Time
+	shortCode string
+}
+
+// encodeCursor makes an opaque cursor pointing just past link
+func encodeCursor(link Link) string {
+	raw := fmt.Sprintf("%d:%s", link.CreatedAt.UnixNano(), link.ShortCode)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(cursor string) (listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return listCursor{}, ErrInvalidCursor
+	}
+
+	nanos, code, found := strings.Cut(string(raw), ":")
+	if !found || code == "" {
+		return listCursor{}, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return listCursor{}, ErrInvalidCursor
+	}
+
+	return listCursor{createdAt: time.Unix(0, n), shortCode: code}, nil
+}
+
+// before reports whether a sorts ahead of b in listing order
+func before(a, b Link) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ShortCode < b.ShortCode
+}
+
+// after reports whether link sorts strictly after the cursor position
+func (c listCursor) after(link Link) bool {
+	return before(Link{CreatedAt: c.createdAt, ShortCode: c.shortCode}, link)
+}
+
+// matchesFilter applies the Query and Host filters of opts to link
+func matchesFilter(link Link, opts ListOptions) bool {
+	if opts.Query != "" {
+		q := strings.ToLower(opts.Query)
+		if !strings.Contains(strings.ToLower(link.ShortCode), q) &&
+			!strings.Contains(strings.ToLower(link.LongURL), q) {
+			return false
+		}
+	}
+
+	if opts.Host != "" {
+		parsed, err := url.Parse(link.LongURL)
+		if err != nil || !strings.EqualFold(parsed.Hostname(), opts.Host) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// listLimit is the page size requested by opts, defaulted and capped
+func listLimit(opts ListOptions) int {
+	if opts.Limit <= 0 {
+		return DefaultListLimit
+	}
+	if opts.Limit > MaxListLimit {
+		return MaxListLimit
+	}
+	return opts.Limit
+}
+
+// paginate sorts, filters and slices links into the page selected by opts.
+// Backends without native ordered scans collect candidates and call this.
+func paginate(links []Link, opts ListOptions) (ListPage, error) {
+	limit := listLimit(opts)
+
+	var cursor *listCursor
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return ListPage{}, err
+		}
+		cursor = &c
+	}
+
+	sort.Slice(links, func(i, j int) bool { return before(links[i], links[j]) })
+
+	page := ListPage{Links: []Link{}}
+	for _, link := range links {
+		if cursor != nil && !cursor.after(link) {
+			continue
+		}
+		if !matchesFilter(link, opts) {
+			continue
+		}
+		if len(page.Links) == limit {
+			page.NextCursor = encodeCursor(page.Links[limit-1])
+			break
+		}
+		page.Links = append(page.Links, link)
+	}
+
+	return page, nil
+}
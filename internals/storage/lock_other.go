@@ -0,0 +1,19 @@
+//go:build !unix
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockDir only creates dir's lock file where flock is unavailable, so
+// nothing stops a second process from opening the same directory
+func lockDir(dir string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("storage: open lock: %w", err)
+	}
+	return f, nil
+}
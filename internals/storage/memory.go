@@ -1,73 +1,316 @@
 package storage
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"sync"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/logging"
 )
 
 // MemoryStorage implements Storage using in-memory maps
 type MemoryStorage struct {
-	shortToLong map[string]string
+	shortToLong map[string]Link
 	longToShort map[string]string
 	mu          sync.RWMutex
+	logger      *slog.Logger
+
+	janitorOnce sync.Once
+	closeOnce   sync.Once
+	stopJanitor chan struct{}
 }
 
-// NewMemoryStorage creates a new in-memory storage
-func NewMemoryStorage() *MemoryStorage {
+// NewMemoryStorage creates a new in-memory storage. A nil logger uses
+// slog.Default().
+func NewMemoryStorage(logger *slog.Logger) *MemoryStorage {
 	return &MemoryStorage{
-		shortToLong: make(map[string]string),
+		shortToLong: make(map[string]Link),
 		longToShort: make(map[string]string),
+		logger:      logging.OrDefault(logger).With("component", "storage"),
+		stopJanitor: make(chan struct{}),
+	}
+}
+
+// map of shortCode to longURL, ErrAlreadyExists if shortCode maps to a different URL
+func (m *MemoryStorage) Save(ctx context.Context, link Link) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFree(link); err != nil {
+		return err
+	}
+
+	m.logger.Debug("Save", "short_code", link.ShortCode, logging.URL("long_url", link.LongURL))
+	m.shortToLong[link.ShortCode] = m.withCreatedAt(link)
+	m.longToShort[link.LongURL] = link.ShortCode
+
+	return nil
+}
+
+// map of alias to longURL, ErrAlreadyExists if alias maps to a different URL
+func (m *MemoryStorage) SaveAlias(ctx context.Context, link Link) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkFree(link); err != nil {
+		return err
+	}
+
+	m.logger.Debug("SaveAlias", "alias", link.ShortCode, logging.URL("long_url", link.LongURL))
+	m.shortToLong[link.ShortCode] = m.withCreatedAt(link)
+
+	return nil
+}
+
+// point shortCode at longURL, moving the reverse lookup along with it
+func (m *MemoryStorage) Update(ctx context.Context, shortCode, longURL string) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	link, exists := m.shortToLong[shortCode]
+	if !exists {
+		m.logger.Debug("Update not found", "short_code", shortCode)
+		return ErrNotFound
+	}
+
+	// only a code that was the reverse lookup for its old URL becomes the
+	// reverse lookup for the new one, and never at the expense of another code
+	indexed := m.longToShort[link.LongURL] == shortCode
+	if indexed {
+		delete(m.longToShort, link.LongURL)
+	}
+	if _, taken := m.longToShort[longURL]; indexed && !taken {
+		m.longToShort[longURL] = shortCode
+	}
+
+	m.logger.Debug("Update", "short_code", shortCode, logging.URL("old", link.LongURL), logging.URL("new", longURL))
+	link.LongURL = longURL
+	m.shortToLong[shortCode] = link
+
+	return nil
 }
 
-// map of shortCode to longURL
-func (m *MemoryStorage) Save(shortCode, longURL string) error {
+// remove shortCode and its reverse lookup
+func (m *MemoryStorage) Delete(ctx context.Context, shortCode string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	log.Printf("storage: Save - shortCode=%s longURL=%s", shortCode, longURL)
-	m.shortToLong[shortCode] = longURL
-	m.longToShort[longURL] = shortCode
+	link, exists := m.shortToLong[shortCode]
+	if !exists {
+		m.logger.Debug("Delete not found", "short_code", shortCode)
+		return ErrNotFound
+	}
+
+	delete(m.shortToLong, shortCode)
+	if m.longToShort[link.LongURL] == shortCode {
+		delete(m.longToShort, link.LongURL)
+	}
 
+	m.logger.Debug("Delete", "short_code", shortCode, logging.URL("long_url", link.LongURL))
 	return nil
 }
 
+// retrieve the full link by shortCode
+func (m *MemoryStorage) GetLink(ctx context.Context, shortCode string) (Link, error) {
+	if err := ctx.Err(); err != nil {
+		return Link{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	link, exists := m.shortToLong[shortCode]
+	if !exists {
+		m.logger.Debug("GetLink not found", "short_code", shortCode)
+		return Link{}, ErrNotFound
+	}
+
+	return link, nil
+}
+
 // retrieve longURL by shortCode
-func (m *MemoryStorage) GetLongURL(shortCode string) (string, error) {
+func (m *MemoryStorage) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	longURL, exists := m.shortToLong[shortCode]
+	link, exists := m.shortToLong[shortCode]
 	if !exists {
-		log.Printf("storage: GetLongURL - not found shortCode=%s", shortCode)
+		m.logger.Debug("GetLongURL not found", "short_code", shortCode)
 		return "", ErrNotFound
 	}
+	if link.Expired(time.Now()) {
+		m.logger.Debug("GetLongURL expired", "short_code", shortCode)
+		return "", ErrExpired
+	}
 
-	log.Printf("storage: GetLongURL - found shortCode=%s longURL=%s", shortCode, longURL)
-	return longURL, nil
+	m.logger.Debug("GetLongURL found", "short_code", shortCode, logging.URL("long_url", link.LongURL))
+	return link.LongURL, nil
 }
 
 // retrieve shortCode by longURL
-func (m *MemoryStorage) GetShortCode(longURL string) (string, error) {
+func (m *MemoryStorage) GetShortCode(ctx context.Context, longURL string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	shortCode, exists := m.longToShort[longURL]
-	if !exists {
-		log.Printf("storage: GetShortCode - not found longURL=%s", longURL)
+	if !exists || m.shortToLong[shortCode].Expired(time.Now()) {
+		m.logger.Debug("GetShortCode not found", logging.URL("long_url", longURL))
 		return "", ErrNotFound
 	}
 
-	log.Printf("storage: GetShortCode - found longURL=%s shortCode=%s", longURL, shortCode)
+	m.logger.Debug("GetShortCode found", logging.URL("long_url", longURL), "short_code", shortCode)
 	return shortCode, nil
 }
 
 // check if shortCode exists
-func (m *MemoryStorage) Exists(shortCode string) bool {
+func (m *MemoryStorage) Exists(ctx context.Context, shortCode string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	_, exists := m.shortToLong[shortCode]
-	log.Printf("storage: Exists - shortCode=%s exists=%v", shortCode, exists)
+	link, exists := m.shortToLong[shortCode]
+	exists = exists && !link.Expired(time.Now())
+	m.logger.Debug("Exists", "short_code", shortCode, "exists", exists)
 	return exists
 }
+
+// page through links ordered by creation time
+func (m *MemoryStorage) List(ctx context.Context, opts ListOptions) (ListPage, error) {
+	if err := ctx.Err(); err != nil {
+		return ListPage{}, err
+	}
+
+	m.mu.RLock()
+	links := make([]Link, 0, len(m.shortToLong))
+	for _, link := range m.shortToLong {
+		links = append(links, link)
+	}
+	m.mu.RUnlock()
+
+	page, err := paginate(links, opts)
+	if err != nil {
+		m.logger.Debug("List invalid cursor", "cursor", opts.Cursor)
+		return ListPage{}, err
+	}
+
+	m.logger.Debug("List", "returned", len(page.Links), "q", opts.Query, "host", opts.Host)
+	return page, nil
+}
+
+// Len returns how many links are held, expired ones not yet purged included
+func (m *MemoryStorage) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.shortToLong)
+}
+
+// PurgeExpired removes every expired link from both maps and returns how
+// many were reclaimed
+func (m *MemoryStorage) PurgeExpired() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	purged := 0
+	for code, link := range m.shortToLong {
+		if !link.Expired(now) {
+			continue
+		}
+		delete(m.shortToLong, code)
+		if m.longToShort[link.LongURL] == code {
+			delete(m.longToShort, link.LongURL)
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		m.logger.Info("PurgeExpired", "reclaimed", purged)
+	}
+	return purged
+}
+
+// StartJanitor reclaims expired links every interval until Close is called
+func (m *MemoryStorage) StartJanitor(interval time.Duration) {
+	m.janitorOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					m.PurgeExpired()
+				case <-m.stopJanitor:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Close stops the janitor, if running
+func (m *MemoryStorage) Close() error {
+	m.closeOnce.Do(func() { close(m.stopJanitor) })
+	return nil
+}
+
+// checkFree rejects link if its code is held by a live link to another URL.
+// Caller must hold m.mu.
+func (m *MemoryStorage) checkFree(link Link) error {
+	existing, exists := m.shortToLong[link.ShortCode]
+	if !exists || existing.LongURL == link.LongURL {
+		return nil
+	}
+	if existing.Expired(time.Now()) {
+		// an expired link gives up its code
+		if m.longToShort[existing.LongURL] == link.ShortCode {
+			delete(m.longToShort, existing.LongURL)
+		}
+		return nil
+	}
+
+	m.logger.Debug("Save collision", "short_code", link.ShortCode, logging.URL("existing", existing.LongURL), logging.URL("new", link.LongURL))
+	return ErrAlreadyExists
+}
+
+// withCreatedAt stamps the creation time on new links and keeps it when a
+// live link is re-saved. Caller must hold m.mu.
+func (m *MemoryStorage) withCreatedAt(link Link) Link {
+	if !link.CreatedAt.IsZero() {
+		return link
+	}
+	if existing, exists := m.shortToLong[link.ShortCode]; exists && !existing.Expired(time.Now()) {
+		link.CreatedAt = existing.CreatedAt
+	} else {
+		link.CreatedAt = time.Now()
+	}
+	return link
+}
@@ -0,0 +1,550 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// attempts at an optimistic WATCH/MULTI transaction before giving up
+const redisTxRetries = 8
+
+// RedisOptions configures RedisStorage
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// prepended to every key, so several deployments can share one server
+	Prefix string
+
+	// how long an expired link keeps answering ErrExpired before Redis drops
+	// it through its native key TTL
+	ExpiredRetention time.Duration
+
+	Logger *slog.Logger // nil uses slog.Default()
+}
+
+// DefaultRedisOptions returns the options used when none are given
+func DefaultRedisOptions() RedisOptions {
+	return RedisOptions{
+		Addr:             "localhost:6379",
+		Prefix:           "urlshort:",
+		ExpiredRetention: time.Minute,
+	}
+}
+
+// RedisStorage implements Storage over the Redis protocol so that several
+// replicas can share one set of links.
+//
+// Keys, all under the configured prefix:
+//
+//	link:<code>  hash of url, created_at and expires_at (unix nanos), domains
+//	             and redirect_status
+//	url:<url>    string holding the code, the reverse lookup
+//	links        sorted set ordering codes for List, see indexMember
+//	expiries     sorted set of the index members of expiring links, scored by
+//	             expires_at (unix millis), so PurgeExpired reads only what is due
+type RedisStorage struct {
+	client *redis.Client
+	opts   RedisOptions
+	logger *slog.Logger
+
+	janitorOnce sync.Once
+	closeOnce   sync.Once
+	stopJanitor chan struct{}
+}
+
+// NewRedisStorage connects to the server in opts and checks it is reachable
+func NewRedisStorage(opts RedisOptions) (*RedisStorage, error) {
+	if opts.ExpiredRetention < 0 {
+		opts.ExpiredRetention = 0
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("storage: connect redis: %w", err)
+	}
+
+	r := &RedisStorage{
+		client:      client,
+		opts:        opts,
+		logger:      logging.OrDefault(opts.Logger).With("component", "storage"),
+		stopJanitor: make(chan struct{}),
+	}
+	r.logger.Info("NewRedisStorage connected", "addr", opts.Addr, "db", opts.DB, "prefix", opts.Prefix)
+	return r, nil
+}
+
+// map of shortCode to longURL, ErrAlreadyExists if shortCode maps to a different URL
+func (r *RedisStorage) Save(ctx context.Context, link Link) error {
+	if err := r.save(ctx, link, true); err != nil {
+		return err
+	}
+
+	r.logger.Debug("Save", "short_code", link.ShortCode, logging.URL("long_url", link.LongURL))
+	return nil
+}
+
+// map of alias to longURL, ErrAlreadyExists if alias maps to a different URL
+func (r *RedisStorage) SaveAlias(ctx context.Context, link Link) error {
+	if err := r.save(ctx, link, false); err != nil {
+		return err
+	}
+
+	r.logger.Debug("SaveAlias", "alias", link.ShortCode, logging.URL("long_url", link.LongURL))
+	return nil
+}
+
+// save writes link and, if indexed, its reverse lookup in one MULTI/EXEC
+func (r *RedisStorage) save(ctx context.Context, link Link, indexed bool) error {
+	linkKey := r.linkKey(link.ShortCode)
+
+	return r.watch(ctx, func(tx *redis.Tx) error {
+		existing, found, err := r.readLink(ctx, tx, link.ShortCode)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		live := found && !existing.Expired(now)
+		var staleURL string
+		if found && existing.LongURL != link.LongURL {
+			if live {
+				r.logger.Debug("Save collision", "short_code", link.ShortCode, logging.URL("existing", existing.LongURL), logging.URL("new", link.LongURL))
+				return ErrAlreadyExists
+			}
+			// an expired link gives up its code
+			if err := tx.Watch(ctx, r.urlKey(existing.LongURL)).Err(); err != nil {
+				return err
+			}
+			if code, _ := tx.Get(ctx, r.urlKey(existing.LongURL)).Result(); code == link.ShortCode {
+				staleURL = existing.LongURL
+			}
+		}
+
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+			if live {
+				link.CreatedAt = existing.CreatedAt
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if found {
+				pipe.ZRem(ctx, r.indexKey(), indexMember(existing))
+				pipe.ZRem(ctx, r.expiriesKey(), indexMember(existing))
+			}
+			if staleURL != "" {
+				pipe.Del(ctx, r.urlKey(staleURL))
+			}
+			pipe.Del(ctx, linkKey)
+			pipe.HSet(ctx, linkKey, linkFields(link))
+			r.expireAt(ctx, pipe, linkKey, link)
+			pipe.ZAdd(ctx, r.indexKey(), redis.Z{Member: indexMember(link)})
+			if !link.ExpiresAt.IsZero() {
+				pipe.ZAdd(ctx, r.expiriesKey(), redis.Z{Score: float64(link.ExpiresAt.UnixMilli()), Member: indexMember(link)})
+			}
+			if indexed {
+				pipe.Set(ctx, r.urlKey(link.LongURL), link.ShortCode, 0)
+				r.expireAt(ctx, pipe, r.urlKey(link.LongURL), link)
+			}
+			return nil
+		})
+		return err
+	}, linkKey)
+}
+
+// point shortCode at longURL, moving the reverse lookup along with it
+func (r *RedisStorage) Update(ctx context.Context, shortCode, longURL string) error {
+	var oldURL string
+
+	err := r.watch(ctx, func(tx *redis.Tx) error {
+		link, found, err := r.readLink(ctx, tx, shortCode)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrNotFound
+		}
+		oldURL = link.LongURL
+
+		// watch both reverse entries so a concurrent writer aborts this update
+		if err := tx.Watch(ctx, r.urlKey(oldURL), r.urlKey(longURL)).Err(); err != nil {
+			return err
+		}
+
+		// only a code that was the reverse lookup for its old URL becomes the
+		// reverse lookup for the new one, and never at the expense of another code
+		code, _ := tx.Get(ctx, r.urlKey(oldURL)).Result()
+		indexed := code == shortCode
+		taken, err := tx.Exists(ctx, r.urlKey(longURL)).Result()
+		if err != nil {
+			return err
+		}
+
+		link.LongURL = longURL
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, r.linkKey(shortCode), "url", longURL)
+			if indexed {
+				pipe.Del(ctx, r.urlKey(oldURL))
+			}
+			if indexed && taken == 0 {
+				pipe.Set(ctx, r.urlKey(longURL), shortCode, 0)
+				r.expireAt(ctx, pipe, r.urlKey(longURL), link)
+			}
+			return nil
+		})
+		return err
+	}, r.linkKey(shortCode))
+	if err == ErrNotFound {
+		r.logger.Debug("Update not found", "short_code", shortCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.logger.Debug("Update", "short_code", shortCode, logging.URL("old", oldURL), logging.URL("new", longURL))
+	return nil
+}
+
+// remove shortCode and its reverse lookup
+func (r *RedisStorage) Delete(ctx context.Context, shortCode string) error {
+	var longURL string
+
+	err := r.watch(ctx, func(tx *redis.Tx) error {
+		link, found, err := r.readLink(ctx, tx, shortCode)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrNotFound
+		}
+		longURL = link.LongURL
+		return r.remove(ctx, tx, link)
+	}, r.linkKey(shortCode))
+	if err == ErrNotFound {
+		r.logger.Debug("Delete not found", "short_code", shortCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.logger.Debug("Delete", "short_code", shortCode, logging.URL("long_url", longURL))
+	return nil
+}
+
+// retrieve the full link by shortCode
+func (r *RedisStorage) GetLink(ctx context.Context, shortCode string) (Link, error) {
+	link, found, err := r.readLink(ctx, r.client, shortCode)
+	if err != nil {
+		return Link{}, err
+	}
+	if !found {
+		r.logger.Debug("GetLink not found", "short_code", shortCode)
+		return Link{}, ErrNotFound
+	}
+	return link, nil
+}
+
+// retrieve longURL by shortCode
+func (r *RedisStorage) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	link, found, err := r.readLink(ctx, r.client, shortCode)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		r.logger.Debug("GetLongURL not found", "short_code", shortCode)
+		return "", ErrNotFound
+	}
+	if link.Expired(time.Now()) {
+		r.logger.Debug("GetLongURL expired", "short_code", shortCode)
+		return "", ErrExpired
+	}
+
+	r.logger.Debug("GetLongURL found", "short_code", shortCode, logging.URL("long_url", link.LongURL))
+	return link.LongURL, nil
+}
+
+// retrieve shortCode by longURL
+func (r *RedisStorage) GetShortCode(ctx context.Context, longURL string) (string, error) {
+	shortCode, err := r.client.Get(ctx, r.urlKey(longURL)).Result()
+	if err != nil && err != redis.Nil {
+		return "", fmt.Errorf("storage: redis get short code: %w", err)
+	}
+	if err == nil {
+		link, found, err := r.readLink(ctx, r.client, shortCode)
+		if err != nil {
+			return "", err
+		}
+		if found && !link.Expired(time.Now()) {
+			r.logger.Debug("GetShortCode found", logging.URL("long_url", longURL), "short_code", shortCode)
+			return shortCode, nil
+		}
+	}
+
+	r.logger.Debug("GetShortCode not found", logging.URL("long_url", longURL))
+	return "", ErrNotFound
+}
+
+// check if shortCode exists
+func (r *RedisStorage) Exists(ctx context.Context, shortCode string) bool {
+	link, found, err := r.readLink(ctx, r.client, shortCode)
+	if err != nil {
+		r.logger.Error("Exists query failed", "short_code", shortCode, "err", err)
+	}
+
+	exists := found && !link.Expired(time.Now())
+	r.logger.Debug("Exists", "short_code", shortCode, "exists", exists)
+	return exists
+}
+
+// page through links ordered by creation time
+func (r *RedisStorage) List(ctx context.Context, opts ListOptions) (ListPage, error) {
+	limit := listLimit(opts)
+
+	start := "-"
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			r.logger.Debug("List invalid cursor", "cursor", opts.Cursor)
+			return ListPage{}, err
+		}
+		start = "(" + indexMember(Link{CreatedAt: c.createdAt, ShortCode: c.shortCode})
+	}
+
+	page := ListPage{Links: []Link{}}
+	for {
+		members, err := r.client.ZRangeByLex(ctx, r.indexKey(), &redis.ZRangeBy{
+			Min:   start,
+			Max:   "+",
+			Count: int64(limit + 1),
+		}).Result()
+		if err != nil {
+			return ListPage{}, fmt.Errorf("storage: redis list: %w", err)
+		}
+
+		for _, member := range members {
+			start = "(" + member
+
+			link, found, err := r.readLink(ctx, r.client, memberCode(member))
+			if err != nil {
+				return ListPage{}, err
+			}
+			if !found || indexMember(link) != member {
+				// the link was dropped by its TTL; tidy up the index
+				r.client.ZRem(ctx, r.indexKey(), member)
+				continue
+			}
+			if !matchesFilter(link, opts) {
+				continue
+			}
+			if len(page.Links) == limit {
+				page.NextCursor = encodeCursor(page.Links[limit-1])
+				break
+			}
+			page.Links = append(page.Links, link)
+		}
+
+		if page.NextCursor != "" || len(members) <= limit {
+			break
+		}
+	}
+
+	r.logger.Debug("List", "returned", len(page.Links), "q", opts.Query, "host", opts.Host)
+	return page, nil
+}
+
+// PurgeExpired removes every link that has expired by now and its reverse
+// lookup, along with index entries left behind by keys Redis already expired,
+// and returns how many links were reclaimed. Only links due in the expiries
+// set are read, so a sweep costs nothing while nothing expires. Links saved
+// before that set existed are left to their native TTL and to List.
+func (r *RedisStorage) PurgeExpired() int {
+	ctx := context.Background()
+	now := time.Now()
+	purged := 0
+
+	members, err := r.client.ZRangeByScore(ctx, r.expiriesKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		r.logger.Error("PurgeExpired failed", "err", err)
+		return 0
+	}
+
+	for _, member := range members {
+		code := memberCode(member)
+		err := r.watch(ctx, func(tx *redis.Tx) error {
+			link, found, err := r.readLink(ctx, tx, code)
+			if err != nil {
+				return err
+			}
+			if !found || indexMember(link) != member {
+				_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					pipe.ZRem(ctx, r.indexKey(), member)
+					pipe.ZRem(ctx, r.expiriesKey(), member)
+					return nil
+				})
+				return err
+			}
+			if !link.Expired(now) {
+				return nil
+			}
+			if err := r.remove(ctx, tx, link); err != nil {
+				return err
+			}
+			purged++
+			return nil
+		}, r.linkKey(code))
+		if err != nil {
+			r.logger.Error("PurgeExpired failed", "short_code", code, "err", err)
+		}
+	}
+
+	if purged > 0 {
+		r.logger.Info("PurgeExpired", "reclaimed", purged)
+	}
+	return purged
+}
+
+// StartJanitor reclaims expired links every interval until Close is called
+func (r *RedisStorage) StartJanitor(interval time.Duration) {
+	r.janitorOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					r.PurgeExpired()
+				case <-r.stopJanitor:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Ping checks the server answers
+func (r *RedisStorage) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Close stops the janitor and closes the connection pool
+func (r *RedisStorage) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.stopJanitor)
+		err = r.client.Close()
+	})
+	return err
+}
+
+// watch runs fn in an optimistic transaction on keys, retrying when another
+// client modifies a watched key first
+func (r *RedisStorage) watch(ctx context.Context, fn func(tx *redis.Tx) error, keys ...string) error {
+	for i := 0; i < redisTxRetries; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := r.client.Watch(ctx, fn, keys...)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return fmt.Errorf("storage: redis transaction on %v kept conflicting", keys)
+}
+
+// remove deletes link, its index entry and its reverse lookup if it holds it.
+// Must run inside watch with the link key watched.
+func (r *RedisStorage) remove(ctx context.Context, tx *redis.Tx, link Link) error {
+	code, _ := tx.Get(ctx, r.urlKey(link.LongURL)).Result()
+
+	_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.linkKey(link.ShortCode))
+		pipe.ZRem(ctx, r.indexKey(), indexMember(link))
+		pipe.ZRem(ctx, r.expiriesKey(), indexMember(link))
+		if code == link.ShortCode {
+			pipe.Del(ctx, r.urlKey(link.LongURL))
+		}
+		return nil
+	})
+	return err
+}
+
+// readLink loads a link hash, found is false if the key does not exist
+func (r *RedisStorage) readLink(ctx context.Context, c redis.Cmdable, shortCode string) (Link, bool, error) {
+	fields, err := c.HGetAll(ctx, r.linkKey(shortCode)).Result()
+	if err != nil {
+		return Link{}, false, fmt.Errorf("storage: redis get link: %w", err)
+	}
+	if len(fields) == 0 {
+		return Link{}, false, nil
+	}
+
+	createdAt, _ := strconv.ParseInt(fields["created_at"], 10, 64)
+	expiresAt, _ := strconv.ParseInt(fields["expires_at"], 10, 64)
+	redirectStatus, _ := strconv.Atoi(fields["redirect_status"]) // absent on older links
+	return Link{
+		ShortCode:      shortCode,
+		LongURL:        fields["url"],
+		CreatedAt:      time.Unix(0, createdAt),
+		ExpiresAt:      fromNanos(expiresAt),
+		Domains:        splitDomains(fields["domains"]),
+		RedirectStatus: redirectStatus,
+	}, true, nil
+}
+
+// expireAt hands key to Redis' native TTL once link has been expired for
+// ExpiredRetention; links without an expiry are kept forever
+func (r *RedisStorage) expireAt(ctx context.Context, pipe redis.Pipeliner, key string, link Link) {
+	if link.ExpiresAt.IsZero() {
+		return
+	}
+	pipe.PExpireAt(ctx, key, link.ExpiresAt.Add(r.opts.ExpiredRetention))
+}
+
+func (r *RedisStorage) linkKey(shortCode string) string { return r.opts.Prefix + "link:" + shortCode }
+func (r *RedisStorage) urlKey(longURL string) string    { return r.opts.Prefix + "url:" + longURL }
+func (r *RedisStorage) indexKey() string                { return r.opts.Prefix + "links" }
+func (r *RedisStorage) expiriesKey() string             { return r.opts.Prefix + "expiries" }
+
+// linkFields is the hash stored under a link key
+func linkFields(link Link) map[string]any {
+	return map[string]any{
+		"url":             link.LongURL,
+		"created_at":      link.CreatedAt.UnixNano(),
+		"expires_at":      toNanos(link.ExpiresAt),
+		"domains":         joinDomains(link.Domains),
+		"redirect_status": link.RedirectStatus,
+	}
+}
+
+// indexMember is the sorted set member for link. All members share score 0,
+// so Redis orders them lexically: the zero-padded creation time followed by
+// the code gives the same order as List on the other backends.
+func indexMember(link Link) string {
+	return fmt.Sprintf("%020d:%s", link.CreatedAt.UnixNano(), link.ShortCode)
+}
+
+// memberCode extracts the short code from an index member
+func memberCode(member string) string {
+	_, code, _ := strings.Cut(member, ":")
+	return code
+}
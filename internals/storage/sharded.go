@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/logging"
+)
+
+// DefaultShardCount is the number of shards used when none is given
+const DefaultShardCount = 64
+
+// codeShard holds the links whose short code hashes to it. Writers
+// serialize on mu; readers never take it.
+type codeShard struct {
+	mu    sync.Mutex
+	links sync.Map // shortCode -> Link
+	count atomic.Int64
+}
+
+// ShardedMemoryStorage implements Storage like MemoryStorage, but splits
+// both maps into shards: links by a hash of the short code and the reverse
+// lookup by a hash of the long URL. Lookups are lock-free, and a write only
+// blocks other writes to codes in the same shard, so bulk imports no longer
+// stall redirects.
+type ShardedMemoryStorage struct {
+	codes []codeShard
+	urls  []sync.Map // longURL -> shortCode
+	mask  uint32
+
+	logger *slog.Logger
+
+	janitorOnce sync.Once
+	closeOnce   sync.Once
+	stopJanitor chan struct{}
+}
+
+// NewShardedMemoryStorage creates an in-memory storage with shards shards,
+// rounded up to a power of two. shards <= 0 uses DefaultShardCount. A nil
+// logger uses slog.Default().
+func NewShardedMemoryStorage(shards int, logger *slog.Logger) *ShardedMemoryStorage {
+	if shards <= 0 {
+		shards = DefaultShardCount
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	return &ShardedMemoryStorage{
+		codes:       make([]codeShard, n),
+		urls:        make([]sync.Map, n),
+		mask:        uint32(n - 1),
+		logger:      logging.OrDefault(logger).With("component", "storage"),
+		stopJanitor: make(chan struct{}),
+	}
+}
+
+// map of shortCode to longURL, ErrAlreadyExists if shortCode maps to a different URL
+func (m *ShardedMemoryStorage) Save(ctx context.Context, link Link) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	shard := m.codeShard(link.ShortCode)
+	shard.mu.Lock()
+	err := m.store(shard, link)
+	if err == nil {
+		m.urlShard(link.LongURL).Store(link.LongURL, link.ShortCode)
+	}
+	shard.mu.Unlock()
+
+	if err != nil {
+		m.logger.Debug("Save collision", "short_code", link.ShortCode, logging.URL("new", link.LongURL))
+		return err
+	}
+	m.logger.Debug("Save", "short_code", link.ShortCode, logging.URL("long_url", link.LongURL))
+	return nil
+}
+
+// map of alias to longURL, ErrAlreadyExists if alias maps to a different URL
+func (m *ShardedMemoryStorage) SaveAlias(ctx context.Context, link Link) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	shard := m.codeShard(link.ShortCode)
+	shard.mu.Lock()
+	err := m.store(shard, link)
+	shard.mu.Unlock()
+
+	if err != nil {
+		m.logger.Debug("SaveAlias collision", "alias", link.ShortCode, logging.URL("new", link.LongURL))
+		return err
+	}
+	m.logger.Debug("SaveAlias", "alias", link.ShortCode, logging.URL("long_url", link.LongURL))
+	return nil
+}
+
+// point shortCode at longURL, moving the reverse lookup along with it
+func (m *ShardedMemoryStorage) Update(ctx context.Context, shortCode, longURL string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	shard := m.codeShard(shortCode)
+	shard.mu.Lock()
+	link, exists := loadLink(&shard.links, shortCode)
+	if !exists {
+		shard.mu.Unlock()
+		m.logger.Debug("Update not found", "short_code", shortCode)
+		return ErrNotFound
+	}
+
+	oldURL := link.LongURL
+	link.LongURL = longURL
+	shard.links.Store(shortCode, link)
+
+	// only a code that was the reverse lookup for its old URL becomes the
+	// reverse lookup for the new one, and never at the expense of another code
+	if m.urlShard(oldURL).CompareAndDelete(oldURL, shortCode) {
+		m.urlShard(longURL).LoadOrStore(longURL, shortCode)
+	}
+	shard.mu.Unlock()
+
+	m.logger.Debug("Update", "short_code", shortCode, logging.URL("old", oldURL), logging.URL("new", longURL))
+	return nil
+}
+
+// remove shortCode and its reverse lookup
+func (m *ShardedMemoryStorage) Delete(ctx context.Context, shortCode string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	shard := m.codeShard(shortCode)
+	shard.mu.Lock()
+	link, exists := loadLink(&shard.links, shortCode)
+	if !exists {
+		shard.mu.Unlock()
+		m.logger.Debug("Delete not found", "short_code", shortCode)
+		return ErrNotFound
+	}
+
+	m.urlShard(link.LongURL).CompareAndDelete(link.LongURL, shortCode)
+	shard.links.Delete(shortCode)
+	shard.count.Add(-1)
+	shard.mu.Unlock()
+
+	m.logger.Debug("Delete", "short_code", shortCode, logging.URL("long_url", link.LongURL))
+	return nil
+}
+
+// retrieve the full link by shortCode
+func (m *ShardedMemoryStorage) GetLink(ctx context.Context, shortCode string) (Link, error) {
+	if err := ctx.Err(); err != nil {
+		return Link{}, err
+	}
+
+	link, exists := loadLink(&m.codeShard(shortCode).links, shortCode)
+	if !exists {
+		return Link{}, ErrNotFound
+	}
+	return link, nil
+}
+
+// retrieve longURL by shortCode
+func (m *ShardedMemoryStorage) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	link, exists := loadLink(&m.codeShard(shortCode).links, shortCode)
+	if !exists {
+		return "", ErrNotFound
+	}
+	if link.Expired(time.Now()) {
+		return "", ErrExpired
+	}
+	return link.LongURL, nil
+}
+
+// retrieve shortCode by longURL, ignoring expired links
+func (m *ShardedMemoryStorage) GetShortCode(ctx context.Context, longURL string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	value, exists := m.urlShard(longURL).Load(longURL)
+	if !exists {
+		return "", ErrNotFound
+	}
+	shortCode := value.(string)
+
+	// the two shards are written one after the other, so confirm the link
+	// still points back at longURL before trusting the reverse entry
+	link, exists := loadLink(&m.codeShard(shortCode).links, shortCode)
+	if !exists || link.LongURL != longURL || link.Expired(time.Now()) {
+		return "", ErrNotFound
+	}
+	return shortCode, nil
+}
+
+// check if shortCode exists and has not expired
+func (m *ShardedMemoryStorage) Exists(ctx context.Context, shortCode string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	link, exists := loadLink(&m.codeShard(shortCode).links, shortCode)
+	return exists && !link.Expired(time.Now())
+}
+
+// page through links ordered by creation time
+func (m *ShardedMemoryStorage) List(ctx context.Context, opts ListOptions) (ListPage, error) {
+	if err := ctx.Err(); err != nil {
+		return ListPage{}, err
+	}
+
+	var links []Link
+	for i := range m.codes {
+		m.codes[i].links.Range(func(_, value any) bool {
+			links = append(links, value.(Link))
+			return true
+		})
+	}
+
+	page, err := paginate(links, opts)
+	if err != nil {
+		m.logger.Debug("List invalid cursor", "cursor", opts.Cursor)
+		return ListPage{}, err
+	}
+	return page, nil
+}
+
+// Len returns how many links are held, expired ones not yet purged included
+func (m *ShardedMemoryStorage) Len() int {
+	var n int64
+	for i := range m.codes {
+		n += m.codes[i].count.Load()
+	}
+	return int(n)
+}
+
+// PurgeExpired removes every expired link and its reverse lookup and
+// returns how many were reclaimed. One shard is locked at a time.
+func (m *ShardedMemoryStorage) PurgeExpired() int {
+	now := time.Now()
+	purged := 0
+	for i := range m.codes {
+		shard := &m.codes[i]
+		shard.mu.Lock()
+		shard.links.Range(func(key, value any) bool {
+			link := value.(Link)
+			if link.Expired(now) {
+				m.urlShard(link.LongURL).CompareAndDelete(link.LongURL, link.ShortCode)
+				shard.links.Delete(key)
+				shard.count.Add(-1)
+				purged++
+			}
+			return true
+		})
+		shard.mu.Unlock()
+	}
+
+	if purged > 0 {
+		m.logger.Info("PurgeExpired", "reclaimed", purged)
+	}
+	return purged
+}
+
+// StartJanitor reclaims expired links every interval until Close is called
+func (m *ShardedMemoryStorage) StartJanitor(interval time.Duration) {
+	m.janitorOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					m.PurgeExpired()
+				case <-m.stopJanitor:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Close stops the janitor, if running
+func (m *ShardedMemoryStorage) Close() error {
+	m.closeOnce.Do(func() { close(m.stopJanitor) })
+	return nil
+}
+
+// store writes link into shard unless its code is held by a live link to
+// another URL. An expired holder gives up its code and reverse lookup, and a
+// live re-save keeps its CreatedAt. Caller must hold shard.mu.
+func (m *ShardedMemoryStorage) store(shard *codeShard, link Link) error {
+	now := time.Now()
+	existing, exists := loadLink(&shard.links, link.ShortCode)
+	live := exists && !existing.Expired(now)
+
+	if live && existing.LongURL != link.LongURL {
+		return ErrAlreadyExists
+	}
+	if exists && !live && existing.LongURL != link.LongURL {
+		m.urlShard(existing.LongURL).CompareAndDelete(existing.LongURL, link.ShortCode)
+	}
+
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = now
+		if live {
+			link.CreatedAt = existing.CreatedAt
+		}
+	}
+	shard.links.Store(link.ShortCode, link)
+	if !exists {
+		shard.count.Add(1)
+	}
+	return nil
+}
+
+func (m *ShardedMemoryStorage) codeShard(shortCode string) *codeShard {
+	return &m.codes[fnv32(shortCode)&m.mask]
+}
+
+func (m *ShardedMemoryStorage) urlShard(longURL string) *sync.Map {
+	return &m.urls[fnv32(longURL)&m.mask]
+}
+
+func loadLink(links *sync.Map, shortCode string) (Link, bool) {
+	value, ok := links.Load(shortCode)
+	if !ok {
+		return Link{}, false
+	}
+	return value.(Link), true
+}
+
+// fnv32 is 32-bit FNV-1a, inlined to hash strings without allocating
+func fnv32(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
@@ -0,0 +1,533 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/logging"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are applied in order, each exactly once; the version of a
+// migration is its index plus one. Append new migrations, never edit old ones.
+var sqliteMigrations = []string{
+	// 1: links mirrors shortToLong, long_to_short mirrors longToShort
+	`CREATE TABLE links (
+		short_code TEXT    NOT NULL,
+		long_url   TEXT    NOT NULL,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE UNIQUE INDEX links_short_code ON links (short_code);
+	CREATE INDEX links_created_at ON links (created_at, short_code);
+	CREATE INDEX links_expires_at ON links (expires_at) WHERE expires_at > 0;
+
+	CREATE TABLE long_to_short (
+		long_url   TEXT NOT NULL,
+		short_code TEXT NOT NULL
+	);
+	CREATE UNIQUE INDEX long_to_short_long_url ON long_to_short (long_url);
+	CREATE INDEX long_to_short_short_code ON long_to_short (short_code);`,
+
+	// 2: hosts a link is published on, comma-separated, empty for every host
+	`ALTER TABLE links ADD COLUMN domains TEXT NOT NULL DEFAULT '';`,
+
+	// 3: redirect status of a link, 0 for the server default
+	`ALTER TABLE links ADD COLUMN redirect_status INTEGER NOT NULL DEFAULT 0;`,
+}
+
+// SQLiteStorage implements Storage on an embedded SQLite database. Every
+// mutation runs in a transaction, so the forward and reverse mappings are
+// always written together.
+type SQLiteStorage struct {
+	db     *sql.DB
+	logger *slog.Logger
+
+	janitorOnce sync.Once
+	closeOnce   sync.Once
+	stopJanitor chan struct{}
+}
+
+// NewSQLiteStorage opens (or creates) the database at path and brings its
+// schema up to date. path ":memory:" gives a private in-memory database.
+// A nil logger uses slog.Default().
+func NewSQLiteStorage(path string, logger *slog.Logger) (*SQLiteStorage, error) {
+	dsn := ":memory:"
+	if path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("storage: create dir: %w", err)
+		}
+		dsn = "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_txlock=immediate"
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("storage: open sqlite: %w", err)
+	}
+	// one connection serializes writers, which SQLite does anyway, and keeps
+	// an in-memory database from splitting across connections
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStorage{
+		db:          db,
+		logger:      logging.OrDefault(logger).With("component", "storage"),
+		stopJanitor: make(chan struct{}),
+	}
+
+	version, err := s.migrate()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s.logger.Info("NewSQLiteStorage opened", "path", path, "schema", version)
+	return s, nil
+}
+
+// migrate applies every migration newer than the recorded schema version
+// and returns the resulting version
+func (s *SQLiteStorage) migrate() (int, error) {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return 0, fmt.Errorf("storage: create schema_migrations: %w", err)
+	}
+
+	var version int
+	if err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("storage: read schema version: %w", err)
+	}
+	if version > len(sqliteMigrations) {
+		return 0, fmt.Errorf("storage: schema version %d is newer than this build (%d)", version, len(sqliteMigrations))
+	}
+
+	for ; version < len(sqliteMigrations); version++ {
+		err := s.tx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, sqliteMigrations[version]); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version+1, time.Now().Unix())
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("storage: migration %d: %w", version+1, err)
+		}
+		s.logger.Info("SQLiteStorage applied migration", "migration", version+1)
+	}
+
+	return version, nil
+}
+
+// SchemaVersion returns the schema version recorded in the database
+func (s *SQLiteStorage) SchemaVersion() (int, error) {
+	ctx := context.Background()
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// map of shortCode to longURL, ErrAlreadyExists if shortCode maps to a different URL
+func (s *SQLiteStorage) Save(ctx context.Context, link Link) error {
+	err := s.tx(ctx, func(tx *sql.Tx) error {
+		link, err := s.prepareSave(ctx, tx, link)
+		if err != nil {
+			return err
+		}
+		if err := upsertLink(ctx, tx, link); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO long_to_short (long_url, short_code) VALUES (?, ?)
+			ON CONFLICT (long_url) DO UPDATE SET short_code = excluded.short_code`,
+			link.LongURL, link.ShortCode)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Debug("Save", "short_code", link.ShortCode, logging.URL("long_url", link.LongURL))
+	return nil
+}
+
+// map of alias to longURL, ErrAlreadyExists if alias maps to a different URL
+func (s *SQLiteStorage) SaveAlias(ctx context.Context, link Link) error {
+	err := s.tx(ctx, func(tx *sql.Tx) error {
+		link, err := s.prepareSave(ctx, tx, link)
+		if err != nil {
+			return err
+		}
+		return upsertLink(ctx, tx, link)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Debug("SaveAlias", "alias", link.ShortCode, logging.URL("long_url", link.LongURL))
+	return nil
+}
+
+// point shortCode at longURL, moving the reverse lookup along with it
+func (s *SQLiteStorage) Update(ctx context.Context, shortCode, longURL string) error {
+	var oldURL string
+	err := s.tx(ctx, func(tx *sql.Tx) error {
+		link, err := getLink(ctx, tx, shortCode)
+		if err != nil {
+			return err
+		}
+		oldURL = link.LongURL
+
+		// only a code that was the reverse lookup for its old URL becomes the
+		// reverse lookup for the new one, and never at the expense of another code
+		res, err := tx.ExecContext(ctx, `DELETE FROM long_to_short WHERE long_url = ? AND short_code = ?`, oldURL, shortCode)
+		if err != nil {
+			return err
+		}
+		if indexed, _ := res.RowsAffected(); indexed > 0 {
+			_, err = tx.ExecContext(ctx, `INSERT INTO long_to_short (long_url, short_code) VALUES (?, ?)
+				ON CONFLICT (long_url) DO NOTHING`, longURL, shortCode)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE links SET long_url = ? WHERE short_code = ?`, longURL, shortCode)
+		return err
+	})
+	if err == ErrNotFound {
+		s.logger.Debug("Update not found", "short_code", shortCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.Debug("Update", "short_code", shortCode, logging.URL("old", oldURL), logging.URL("new", longURL))
+	return nil
+}
+
+// remove shortCode and its reverse lookup
+func (s *SQLiteStorage) Delete(ctx context.Context, shortCode string) error {
+	var longURL string
+	err := s.tx(ctx, func(tx *sql.Tx) error {
+		link, err := getLink(ctx, tx, shortCode)
+		if err != nil {
+			return err
+		}
+		longURL = link.LongURL
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM links WHERE short_code = ?`, shortCode); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM long_to_short WHERE long_url = ? AND short_code = ?`, longURL, shortCode)
+		return err
+	})
+	if err == ErrNotFound {
+		s.logger.Debug("Delete not found", "short_code", shortCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.Debug("Delete", "short_code", shortCode, logging.URL("long_url", longURL))
+	return nil
+}
+
+// retrieve the full link by shortCode
+func (s *SQLiteStorage) GetLink(ctx context.Context, shortCode string) (Link, error) {
+	link, err := getLink(ctx, s.db, shortCode)
+	if err == ErrNotFound {
+		s.logger.Debug("GetLink not found", "short_code", shortCode)
+	}
+	return link, err
+}
+
+// retrieve longURL by shortCode
+func (s *SQLiteStorage) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	link, err := getLink(ctx, s.db, shortCode)
+	if err != nil {
+		if err == ErrNotFound {
+			s.logger.Debug("GetLongURL not found", "short_code", shortCode)
+		}
+		return "", err
+	}
+	if link.Expired(time.Now()) {
+		s.logger.Debug("GetLongURL expired", "short_code", shortCode)
+		return "", ErrExpired
+	}
+
+	s.logger.Debug("GetLongURL found", "short_code", shortCode, logging.URL("long_url", link.LongURL))
+	return link.LongURL, nil
+}
+
+// retrieve shortCode by longURL
+func (s *SQLiteStorage) GetShortCode(ctx context.Context, longURL string) (string, error) {
+	var shortCode string
+	var expiresAt int64
+	err := s.db.QueryRowContext(ctx, `SELECT l.short_code, l.expires_at FROM long_to_short r
+		JOIN links l ON l.short_code = r.short_code
+		WHERE r.long_url = ?`, longURL).Scan(&shortCode, &expiresAt)
+	if err == sql.ErrNoRows || (err == nil && (Link{ExpiresAt: fromNanos(expiresAt)}).Expired(time.Now())) {
+		s.logger.Debug("GetShortCode not found", logging.URL("long_url", longURL))
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("storage: sqlite get short code: %w", err)
+	}
+
+	s.logger.Debug("GetShortCode found", logging.URL("long_url", longURL), "short_code", shortCode)
+	return shortCode, nil
+}
+
+// check if shortCode exists
+func (s *SQLiteStorage) Exists(ctx context.Context, shortCode string) bool {
+	link, err := getLink(ctx, s.db, shortCode)
+	if err != nil && err != ErrNotFound {
+		s.logger.Error("Exists query failed", "short_code", shortCode, "err", err)
+	}
+
+	exists := err == nil && !link.Expired(time.Now())
+	s.logger.Debug("Exists", "short_code", shortCode, "exists", exists)
+	return exists
+}
+
+// page through links ordered by creation time
+func (s *SQLiteStorage) List(ctx context.Context, opts ListOptions) (ListPage, error) {
+	limit := listLimit(opts)
+
+	// the index walks links in listing order, starting just past the cursor
+	query := `SELECT short_code, long_url, created_at, expires_at, domains, redirect_status FROM links`
+	var args []any
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			s.logger.Debug("List invalid cursor", "cursor", opts.Cursor)
+			return ListPage{}, err
+		}
+		nanos := c.createdAt.UnixNano()
+		query += ` WHERE created_at > ? OR (created_at = ? AND short_code > ?)`
+		args = append(args, nanos, nanos, c.shortCode)
+	}
+	query += ` ORDER BY created_at, short_code`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return ListPage{}, fmt.Errorf("storage: sqlite list: %w", err)
+	}
+	defer rows.Close()
+
+	page := ListPage{Links: []Link{}}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return ListPage{}, fmt.Errorf("storage: sqlite list: %w", err)
+		}
+		if !matchesFilter(link, opts) {
+			continue
+		}
+		if len(page.Links) == limit {
+			page.NextCursor = encodeCursor(page.Links[limit-1])
+			break
+		}
+		page.Links = append(page.Links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return ListPage{}, fmt.Errorf("storage: sqlite list: %w", err)
+	}
+
+	s.logger.Debug("List", "returned", len(page.Links), "q", opts.Query, "host", opts.Host)
+	return page, nil
+}
+
+// PurgeExpired removes every expired link and its reverse lookup and returns
+// how many were reclaimed
+func (s *SQLiteStorage) PurgeExpired() int {
+	ctx := context.Background()
+	var purged int64
+	now := time.Now().UnixNano()
+	err := s.tx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM long_to_short WHERE short_code IN (
+			SELECT short_code FROM links WHERE expires_at > 0 AND expires_at <= ?)`, now)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM links WHERE expires_at > 0 AND expires_at <= ?`, now)
+		if err != nil {
+			return err
+		}
+		purged, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		s.logger.Error("PurgeExpired failed", "err", err)
+		return 0
+	}
+
+	if purged > 0 {
+		s.logger.Info("PurgeExpired", "reclaimed", purged)
+	}
+	return int(purged)
+}
+
+// StartJanitor reclaims expired links every interval until Close is called
+func (s *SQLiteStorage) StartJanitor(interval time.Duration) {
+	s.janitorOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					s.PurgeExpired()
+				case <-s.stopJanitor:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Ping checks the database answers
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close stops the janitor and closes the database
+func (s *SQLiteStorage) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stopJanitor)
+		err = s.db.Close()
+	})
+	return err
+}
+
+// prepareSave rejects link if its code is held by a live link to another URL,
+// releases the code of an expired holder and fills in CreatedAt the way
+// MemoryStorage does
+func (s *SQLiteStorage) prepareSave(ctx context.Context, tx *sql.Tx, link Link) (Link, error) {
+	now := time.Now()
+	existing, err := getLink(ctx, tx, link.ShortCode)
+	if err == ErrNotFound {
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+		}
+		return link, nil
+	}
+	if err != nil {
+		return Link{}, err
+	}
+
+	live := !existing.Expired(now)
+	if existing.LongURL != link.LongURL {
+		if live {
+			s.logger.Debug("Save collision", "short_code", link.ShortCode, logging.URL("existing", existing.LongURL), logging.URL("new", link.LongURL))
+			return Link{}, ErrAlreadyExists
+		}
+		// an expired link gives up its code
+		_, err := tx.ExecContext(ctx, `DELETE FROM long_to_short WHERE long_url = ? AND short_code = ?`, existing.LongURL, link.ShortCode)
+		if err != nil {
+			return Link{}, err
+		}
+	}
+
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = now
+		if live {
+			link.CreatedAt = existing.CreatedAt
+		}
+	}
+	return link, nil
+}
+
+// tx runs fn in a transaction, committing only if it returns nil
+func (s *SQLiteStorage) tx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("storage: sqlite begin: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("storage: sqlite commit: %w", err)
+	}
+	return nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// getLink loads a single link, ErrNotFound if there is none
+func getLink(ctx context.Context, q queryRower, shortCode string) (Link, error) {
+	row := q.QueryRowContext(ctx, `SELECT short_code, long_url, created_at, expires_at, domains, redirect_status FROM links WHERE short_code = ?`, shortCode)
+	link, err := scanLink(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Link{}, ErrNotFound
+	}
+	if err != nil {
+		return Link{}, fmt.Errorf("storage: sqlite get link: %w", err)
+	}
+	return link, nil
+}
+
+// upsertLink writes link, replacing whatever the code held before
+func upsertLink(ctx context.Context, tx *sql.Tx, link Link) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO links (short_code, long_url, created_at, expires_at, domains, redirect_status) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (short_code) DO UPDATE SET
+			long_url = excluded.long_url,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at,
+			domains = excluded.domains,
+			redirect_status = excluded.redirect_status`,
+		link.ShortCode, link.LongURL, link.CreatedAt.UnixNano(), toNanos(link.ExpiresAt), joinDomains(link.Domains), link.RedirectStatus)
+	return err
+}
+
+// scanLink reads one links row
+func scanLink(row rowScanner) (Link, error) {
+	var link Link
+	var createdAt, expiresAt int64
+	var domains string
+	if err := row.Scan(&link.ShortCode, &link.LongURL, &createdAt, &expiresAt, &domains, &link.RedirectStatus); err != nil {
+		return Link{}, err
+	}
+	link.CreatedAt = time.Unix(0, createdAt)
+	link.ExpiresAt = fromNanos(expiresAt)
+	link.Domains = splitDomains(domains)
+	return link, nil
+}
+
+// toNanos stores a zero time (never expires) as 0
+func toNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromNanos is the inverse of toNanos
+func fromNanos(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -1,24 +1,174 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
 
 var (
 	ErrNotFound      = errors.New("short code not found")
 	ErrAlreadyExists = errors.New("mapping already exists")
+	ErrExpired       = errors.New("short code expired")
+	ErrClosed        = errors.New("storage is closed")
+	ErrLocked        = errors.New("storage is in use by another process")
+)
+
+// Link is a single stored mapping and its metadata
+type Link struct {
+	ShortCode string
+	LongURL   string
+	CreatedAt time.Time
+	ExpiresAt time.Time // zero value means the link never expires
+	Domains   []string  // hosts the link is published on, empty means every host
+	// RedirectStatus is the 3xx status redirects answer with, zero for the
+	// server default
+	RedirectStatus int
+}
+
+// Expired reports whether the link has passed its expiry at now
+func (l Link) Expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
+
+// code rules shared by every way a link is stored - URL-safe characters only,
+// and never a name the router owns
+const (
+	MinCodeLength = 3
+	MaxCodeLength = 64
+)
+
+var (
+	codePattern   = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	reservedCodes = map[string]bool{
+		"api":     true,
+		"admin":   true,
+		"healthz": true,
+		"readyz":  true,
+		"metrics": true,
+	}
 )
 
-// interface for URL storage
+// ValidCode reports whether code may name a link: MinCodeLength to
+// MaxCodeLength URL-safe characters and not a reserved route name
+func ValidCode(code string) bool {
+	return len(code) >= MinCodeLength && len(code) <= MaxCodeLength &&
+		codePattern.MatchString(code) && !reservedCodes[strings.ToLower(code)]
+}
+
+// ValidLongURL reports whether a link may redirect to longURL, an absolute
+// http or https URL
+func ValidLongURL(longURL string) bool {
+	u, err := url.ParseRequestURI(longURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// ValidRedirectStatus reports whether a link may redirect with status:
+// 301 or 308 for permanent links, 302 or 307 for temporary ones
+func ValidRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// PublishedOn reports whether the link is served on host, a lowercase
+// hostname without port
+func (l Link) PublishedOn(host string) bool {
+	return len(l.Domains) == 0 || slices.Contains(l.Domains, host)
+}
+
+// interface for URL storage. Every method gives up with ctx.Err() once ctx
+// is cancelled or past its deadline; Exists then reports false.
 type Storage interface {
 
-	// map of shortCode to longURL
-	Save(shortCode, longURL string) error
+	// map of shortCode to longURL, ErrAlreadyExists if shortCode maps to a different URL
+	Save(ctx context.Context, link Link) error
+
+	// map of alias to longURL without touching the longURL reverse lookup
+	SaveAlias(ctx context.Context, link Link) error
+
+	// point an existing shortCode at a new longURL, ErrNotFound if it does not exist
+	Update(ctx context.Context, shortCode, longURL string) error
+
+	// remove shortCode and its reverse lookup, ErrNotFound if it does not exist
+	Delete(ctx context.Context, shortCode string) error
+
+	// retrieve the full link by shortCode, expired or not
+	GetLink(ctx context.Context, shortCode string) (Link, error)
+
+	// retrieve longURL by shortCode, ErrExpired once the link has expired
+	GetLongURL(ctx context.Context, shortCode string) (string, error)
+
+	// retrieve shortCode by longURL, ignoring expired links
+	GetShortCode(ctx context.Context, longURL string) (string, error)
+
+	// check if shortCode exists and has not expired
+	Exists(ctx context.Context, shortCode string) bool
+
+	// page through links ordered by creation time, expired ones included
+	List(ctx context.Context, opts ListOptions) (ListPage, error)
+}
+
+// Pinger is implemented by backends that can check they are able to serve,
+// such as a reachable server or an open file
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that store can serve requests. A store without a Ping method
+// is always ready.
+func Ping(ctx context.Context, store Storage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p, ok := store.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// joinDomains stores a link's domains as one comma-separated field for the
+// sqlite and redis backends; hostnames never contain a comma
+func joinDomains(domains []string) string {
+	return strings.Join(domains, ",")
+}
+
+// splitDomains is the inverse of joinDomains, nil for every host
+func splitDomains(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
 
-	// retrieve longURL by shortCode
-	GetLongURL(shortCode string) (string, error)
+// Resolver is implemented by backends that can answer a redirect lookup with
+// the link's metadata cheaper than GetLink, such as a cache
+type Resolver interface {
+	Resolve(ctx context.Context, shortCode string) (Link, error)
+}
 
-	// retrieve shortCode by longURL
-	GetShortCode(longURL string) (string, error)
+// Resolve returns the link a redirect for shortCode should follow: like
+// GetLongURL, ErrExpired once it has expired, but with the link's domains
+// and redirect status.
+// CreatedAt is not filled in by every backend.
+func Resolve(ctx context.Context, store Storage, shortCode string) (Link, error) {
+	if r, ok := store.(Resolver); ok {
+		return r.Resolve(ctx, shortCode)
+	}
 
-	// check if shortCode exists
-	Exists(shortCode string) bool
-}
\ No newline at end of file
+	link, err := store.GetLink(ctx, shortCode)
+	if err != nil {
+		return Link{}, err
+	}
+	if link.Expired(time.Now()) {
+		return Link{}, ErrExpired
+	}
+	return link, nil
+}
@@ -0,0 +1,504 @@
+// Package storagetest is a conformance suite for storage.Storage
+// implementations. Every backend runs the same checks, so a new one is
+// proven to behave like MemoryStorage before it is deployed:
+//
+//	func TestMyStorage(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			return newMyStorage(t)
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+// Factory returns a new, empty storage for one subtest. It should register
+// any cleanup with t.Cleanup. Backends that drop expired links on their own
+// must keep them readable for at least a few minutes past their expiry.
+type Factory func(t *testing.T) storage.Storage
+
+// Run checks every storage.Storage contract against storages from newStorage
+func Run(t *testing.T, newStorage Factory) {
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newStorage(t)) })
+	t.Run("SaveAndGet", func(t *testing.T) { testSaveAndGet(t, newStorage(t)) })
+	t.Run("ExistsConsistency", func(t *testing.T) { testExistsConsistency(t, newStorage(t)) })
+	t.Run("ReverseLookup", func(t *testing.T) { testReverseLookup(t, newStorage(t)) })
+	t.Run("UpdateKeepsOtherReverse", func(t *testing.T) { testUpdateKeepsOtherReverse(t, newStorage(t)) })
+	t.Run("Collision", func(t *testing.T) { testCollision(t, newStorage(t)) })
+	t.Run("Expiry", func(t *testing.T) { testExpiry(t, newStorage(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, newStorage(t)) })
+	t.Run("ConcurrentSaveGet", func(t *testing.T) { testConcurrentSaveGet(t, newStorage(t)) })
+	t.Run("ConcurrentCollision", func(t *testing.T) { testConcurrentCollision(t, newStorage(t)) })
+	t.Run("CanceledContext", func(t *testing.T) { testCanceledContext(t, newStorage(t)) })
+	t.Run("Ping", func(t *testing.T) { testPing(t, newStorage(t)) })
+	t.Run("Domains", func(t *testing.T) { testDomains(t, newStorage(t)) })
+	t.Run("RedirectStatus", func(t *testing.T) { testRedirectStatus(t, newStorage(t)) })
+}
+
+// unknown codes and URLs yield ErrNotFound everywhere
+func testNotFound(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	if _, err := s.GetLink(ctx, "missing1"); err != storage.ErrNotFound {
+		t.Fatalf("GetLink: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetLongURL(ctx, "missing1"); err != storage.ErrNotFound {
+		t.Fatalf("GetLongURL: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetShortCode(ctx, "https://www.example.com/missing"); err != storage.ErrNotFound {
+		t.Fatalf("GetShortCode: expected ErrNotFound, got %v", err)
+	}
+	if err := s.Update(ctx, "missing1", "https://www.example.com/new"); err != storage.ErrNotFound {
+		t.Fatalf("Update: expected ErrNotFound, got %v", err)
+	}
+	if err := s.Delete(ctx, "missing1"); err != storage.ErrNotFound {
+		t.Fatalf("Delete: expected ErrNotFound, got %v", err)
+	}
+	if s.Exists(ctx, "missing1") {
+		t.Fatalf("Exists: expected false for unknown code")
+	}
+}
+
+// a saved link reads back with its metadata, and re-saving keeps CreatedAt
+func testSaveAndGet(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	mustSave(t, s, storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/a", ExpiresAt: expiresAt})
+
+	link, err := s.GetLink(ctx, "abc12345")
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if link.ShortCode != "abc12345" || link.LongURL != "https://www.example.com/a" {
+		t.Fatalf("GetLink returned %+v", link)
+	}
+	if link.CreatedAt.IsZero() {
+		t.Fatalf("Expected CreatedAt to be stamped")
+	}
+	if !link.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("Expected ExpiresAt %v, got %v", expiresAt, link.ExpiresAt)
+	}
+
+	if longURL, err := s.GetLongURL(ctx, "abc12345"); err != nil || longURL != "https://www.example.com/a" {
+		t.Fatalf("GetLongURL returned %s, %v", longURL, err)
+	}
+
+	mustSave(t, s, storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/a"})
+	again, _ := s.GetLink(ctx, "abc12345")
+	if !again.CreatedAt.Equal(link.CreatedAt) {
+		t.Fatalf("Expected re-save to keep CreatedAt %v, got %v", link.CreatedAt, again.CreatedAt)
+	}
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+	mustSave(t, s, storage.Link{ShortCode: "given001", LongURL: "https://www.example.com/given", CreatedAt: createdAt})
+	if given, _ := s.GetLink(ctx, "given001"); !given.CreatedAt.Equal(createdAt) {
+		t.Fatalf("Expected explicit CreatedAt %v, got %v", createdAt, given.CreatedAt)
+	}
+}
+
+// Exists agrees with GetLongURL through the life of a link
+func testExistsConsistency(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	check := func(stage, code string) {
+		t.Helper()
+		_, err := s.GetLongURL(ctx, code)
+		if s.Exists(ctx, code) != (err == nil) {
+			t.Fatalf("%s: Exists=%v but GetLongURL err=%v", stage, s.Exists(ctx, code), err)
+		}
+	}
+
+	check("unknown", "code0001")
+	mustSave(t, s, storage.Link{ShortCode: "code0001", LongURL: "https://www.example.com/1"})
+	check("saved", "code0001")
+	mustSaveAlias(t, s, storage.Link{ShortCode: "alias001", LongURL: "https://www.example.com/1"})
+	check("alias", "alias001")
+	mustSave(t, s, storage.Link{ShortCode: "expired1", LongURL: "https://www.example.com/2", ExpiresAt: time.Now().Add(-time.Minute)})
+	check("expired", "expired1")
+	if err := s.Delete(ctx, "code0001"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	check("deleted", "code0001")
+}
+
+// Save maintains the reverse lookup, SaveAlias leaves it alone, and Update
+// and Delete move or remove it
+func testReverseLookup(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSave(t, s, storage.Link{ShortCode: "hash0001", LongURL: "https://www.example.com/a"})
+	mustSaveAlias(t, s, storage.Link{ShortCode: "my-alias", LongURL: "https://www.example.com/a"})
+	mustSaveAlias(t, s, storage.Link{ShortCode: "only-alias", LongURL: "https://www.example.com/aliased"})
+
+	expectShortCode(t, s, "https://www.example.com/a", "hash0001")
+	expectNoShortCode(t, s, "https://www.example.com/aliased")
+
+	if err := s.Delete(ctx, "my-alias"); err != nil {
+		t.Fatalf("Delete alias failed: %v", err)
+	}
+	expectShortCode(t, s, "https://www.example.com/a", "hash0001")
+
+	if err := s.Update(ctx, "hash0001", "https://www.example.com/b"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if longURL, _ := s.GetLongURL(ctx, "hash0001"); longURL != "https://www.example.com/b" {
+		t.Fatalf("Expected updated destination, got %s", longURL)
+	}
+	expectNoShortCode(t, s, "https://www.example.com/a")
+	expectShortCode(t, s, "https://www.example.com/b", "hash0001")
+
+	if err := s.Delete(ctx, "hash0001"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	expectNoShortCode(t, s, "https://www.example.com/b")
+}
+
+// retargeting onto a URL that already has a code never steals its lookup
+func testUpdateKeepsOtherReverse(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSave(t, s, storage.Link{ShortCode: "first001", LongURL: "https://www.example.com/first"})
+	mustSave(t, s, storage.Link{ShortCode: "second01", LongURL: "https://www.example.com/second"})
+
+	if err := s.Update(ctx, "second01", "https://www.example.com/first"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	expectShortCode(t, s, "https://www.example.com/first", "first001")
+	expectNoShortCode(t, s, "https://www.example.com/second")
+}
+
+// a code held by a live link to another URL is never overwritten
+func testCollision(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSave(t, s, storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"})
+	mustSave(t, s, storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"})
+
+	if err := s.Save(ctx, storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/b"}); err != storage.ErrAlreadyExists {
+		t.Fatalf("Save: expected ErrAlreadyExists, got %v", err)
+	}
+	if err := s.SaveAlias(ctx, storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/b"}); err != storage.ErrAlreadyExists {
+		t.Fatalf("SaveAlias: expected ErrAlreadyExists, got %v", err)
+	}
+
+	if longURL, _ := s.GetLongURL(ctx, "samecode"); longURL != "https://www.example.com/a" {
+		t.Fatalf("Expected original mapping to survive, got %s", longURL)
+	}
+	expectShortCode(t, s, "https://www.example.com/a", "samecode")
+	expectNoShortCode(t, s, "https://www.example.com/b")
+}
+
+// expired links are readable through GetLink only and give up their code
+func testExpiry(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+	mustSave(t, s, storage.Link{ShortCode: "expired1", LongURL: "https://www.example.com/old", ExpiresAt: past})
+
+	if _, err := s.GetLongURL(ctx, "expired1"); err != storage.ErrExpired {
+		t.Fatalf("GetLongURL: expected ErrExpired, got %v", err)
+	}
+	if link, err := s.GetLink(ctx, "expired1"); err != nil || !link.Expired(time.Now()) {
+		t.Fatalf("GetLink: expected the expired link, got %+v, %v", link, err)
+	}
+	if s.Exists(ctx, "expired1") {
+		t.Fatalf("Exists: expected false for an expired link")
+	}
+	expectNoShortCode(t, s, "https://www.example.com/old")
+
+	mustSave(t, s, storage.Link{ShortCode: "expired1", LongURL: "https://www.example.com/new"})
+	if longURL, _ := s.GetLongURL(ctx, "expired1"); longURL != "https://www.example.com/new" {
+		t.Fatalf("Expected expired code to be reclaimed, got %s", longURL)
+	}
+	expectShortCode(t, s, "https://www.example.com/new", "expired1")
+	expectNoShortCode(t, s, "https://www.example.com/old")
+}
+
+// List pages in creation order with filters and rejects bad cursors
+func testList(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		code := fmt.Sprintf("list%04d", 6-i)
+		host := "a.example.com"
+		if i%2 == 1 {
+			host = "b.example.com"
+		}
+		// pairs share a timestamp, so the code breaks the tie
+		mustSave(t, s, storage.Link{
+			ShortCode: code,
+			LongURL:   fmt.Sprintf("https://%s/%d", host, i),
+			CreatedAt: base.Add(time.Duration(i/2) * time.Second),
+		})
+	}
+	want := []string{"list0005", "list0006", "list0003", "list0004", "list0001", "list0002", "list0000"}
+
+	if got := listAll(t, s, storage.ListOptions{Limit: 3}); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Expected order %v, got %v", want, got)
+	}
+	if got := listAll(t, s, storage.ListOptions{Limit: 2, Host: "B.EXAMPLE.COM"}); fmt.Sprint(got) != "[list0005 list0003 list0001]" {
+		t.Fatalf("Unexpected host filter result %v", got)
+	}
+	if got := listAll(t, s, storage.ListOptions{Query: "LIST000"}); len(got) != 7 {
+		t.Fatalf("Expected query to match every code, got %v", got)
+	}
+	if got := listAll(t, s, storage.ListOptions{Query: "/4"}); fmt.Sprint(got) != "[list0002]" {
+		t.Fatalf("Expected query to match the long URL, got %v", got)
+	}
+
+	if _, err := s.List(ctx, storage.ListOptions{Cursor: "%%%"}); err != storage.ErrInvalidCursor {
+		t.Fatalf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+// parallel writers and readers on distinct codes never lose or mix links
+func testConcurrentSaveGet(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	const workers, perWorker = 8, 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				code := fmt.Sprintf("c%02d%05d", w, i)
+				longURL := fmt.Sprintf("https://www.example.com/%d/%d", w, i)
+				if err := s.Save(ctx, storage.Link{ShortCode: code, LongURL: longURL}); err != nil {
+					errs <- fmt.Errorf("Save %s: %v", code, err)
+					return
+				}
+				if got, err := s.GetLongURL(ctx, code); err != nil || got != longURL {
+					errs <- fmt.Errorf("GetLongURL %s: got %s, %v", code, got, err)
+					return
+				}
+				if got, err := s.GetShortCode(ctx, longURL); err != nil || got != code {
+					errs <- fmt.Errorf("GetShortCode %s: got %s, %v", longURL, got, err)
+					return
+				}
+				s.Exists(ctx, code)
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if got := listAll(t, s, storage.ListOptions{Limit: storage.MaxListLimit}); len(got) != workers*perWorker {
+		t.Fatalf("Expected %d links, got %d", workers*perWorker, len(got))
+	}
+}
+
+// of many writers racing for one code with different URLs, exactly one wins
+func testConcurrentCollision(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	const workers = 8
+
+	var wg sync.WaitGroup
+	results := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			results <- s.Save(ctx, storage.Link{ShortCode: "contended", LongURL: fmt.Sprintf("https://www.example.com/%d", w)})
+		}(w)
+	}
+	wg.Wait()
+	close(results)
+
+	won := 0
+	for err := range results {
+		switch err {
+		case nil:
+			won++
+		case storage.ErrAlreadyExists:
+		default:
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if won != 1 {
+		t.Fatalf("Expected exactly one winner, got %d", won)
+	}
+
+	winner, _ := s.GetLongURL(ctx, "contended")
+	expectShortCode(t, s, winner, "contended")
+	for w := 0; w < workers; w++ {
+		if url := fmt.Sprintf("https://www.example.com/%d", w); url != winner {
+			expectNoShortCode(t, s, url)
+		}
+	}
+}
+
+// a cancelled context fails every call with context.Canceled and writes nothing
+func testCanceledContext(t *testing.T, s storage.Storage) {
+	mustSave(t, s, storage.Link{ShortCode: "live0001", LongURL: "https://www.example.com/live"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	link := storage.Link{ShortCode: "cancel01", LongURL: "https://www.example.com/cancel"}
+	if err := s.Save(ctx, link); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Save: expected context.Canceled, got %v", err)
+	}
+	if err := s.SaveAlias(ctx, link); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SaveAlias: expected context.Canceled, got %v", err)
+	}
+	if err := s.Update(ctx, "live0001", "https://www.example.com/moved"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Update: expected context.Canceled, got %v", err)
+	}
+	if err := s.Delete(ctx, "live0001"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Delete: expected context.Canceled, got %v", err)
+	}
+	if _, err := s.GetLink(ctx, "live0001"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetLink: expected context.Canceled, got %v", err)
+	}
+	if _, err := s.GetLongURL(ctx, "live0001"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetLongURL: expected context.Canceled, got %v", err)
+	}
+	if _, err := s.GetShortCode(ctx, "https://www.example.com/live"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetShortCode: expected context.Canceled, got %v", err)
+	}
+	if _, err := s.List(ctx, storage.ListOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("List: expected context.Canceled, got %v", err)
+	}
+	if s.Exists(ctx, "live0001") {
+		t.Fatalf("Exists: expected false under a cancelled context")
+	}
+
+	live := context.Background()
+	if s.Exists(live, "cancel01") {
+		t.Fatalf("Expected cancelled save to store nothing")
+	}
+	if longURL, err := s.GetLongURL(live, "live0001"); err != nil || longURL != "https://www.example.com/live" {
+		t.Fatalf("Expected cancelled update and delete to leave the link, got %s, %v", longURL, err)
+	}
+}
+
+// a freshly opened storage is ready, and a cancelled probe is not
+func testPing(t *testing.T, s storage.Storage) {
+	if err := storage.Ping(context.Background(), s); err != nil {
+		t.Fatalf("Expected a new storage to be ready, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := storage.Ping(ctx, s); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// Domains survive Save, re-Save and Update, and Resolve reports them
+func testDomains(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSave(t, s, storage.Link{ShortCode: "domain01", LongURL: "https://www.example.com/pinned", Domains: []string{"a.link"}})
+	mustSaveAlias(t, s, storage.Link{ShortCode: "everywhere", LongURL: "https://www.example.com/any"})
+
+	mustSave(t, s, storage.Link{ShortCode: "domain01", LongURL: "https://www.example.com/pinned", Domains: []string{"a.link", "b.link"}})
+	if link, err := s.GetLink(ctx, "domain01"); err != nil || !slices.Equal(link.Domains, []string{"a.link", "b.link"}) {
+		t.Fatalf("GetLink: expected domains a.link, b.link, got %+v, %v", link, err)
+	}
+
+	if err := s.Update(ctx, "domain01", "https://www.example.com/moved"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	link, err := storage.Resolve(ctx, s, "domain01")
+	if err != nil || link.LongURL != "https://www.example.com/moved" || !slices.Equal(link.Domains, []string{"a.link", "b.link"}) {
+		t.Fatalf("Resolve: expected the moved link on a.link, b.link, got %+v, %v", link, err)
+	}
+	if !link.PublishedOn("b.link") || link.PublishedOn("c.link") {
+		t.Fatalf("Expected link published on b.link only, got %v", link.Domains)
+	}
+
+	if link, err := storage.Resolve(ctx, s, "everywhere"); err != nil || link.Domains != nil || !link.PublishedOn("c.link") {
+		t.Fatalf("Resolve: expected a link served on every host, got %+v, %v", link, err)
+	}
+	if _, err := storage.Resolve(ctx, s, "missing1"); err != storage.ErrNotFound {
+		t.Fatalf("Resolve: expected ErrNotFound, got %v", err)
+	}
+	mustSave(t, s, storage.Link{ShortCode: "expired2", LongURL: "https://www.example.com/gone", ExpiresAt: time.Now().Add(-time.Minute)})
+	if _, err := storage.Resolve(ctx, s, "expired2"); err != storage.ErrExpired {
+		t.Fatalf("Resolve: expected ErrExpired, got %v", err)
+	}
+}
+
+// RedirectStatus survives Save, re-Save and Update, and Resolve reports it
+func testRedirectStatus(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSave(t, s, storage.Link{ShortCode: "redirect1", LongURL: "https://www.example.com/moved", RedirectStatus: 301})
+	if link, err := s.GetLink(ctx, "redirect1"); err != nil || link.RedirectStatus != 301 {
+		t.Fatalf("GetLink: expected status 301, got %+v, %v", link, err)
+	}
+
+	mustSave(t, s, storage.Link{ShortCode: "redirect1", LongURL: "https://www.example.com/moved", RedirectStatus: 308})
+	if err := s.Update(ctx, "redirect1", "https://www.example.com/moved-again"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if link, err := storage.Resolve(ctx, s, "redirect1"); err != nil || link.RedirectStatus != 308 {
+		t.Fatalf("Resolve: expected status 308 after Update, got %+v, %v", link, err)
+	}
+
+	mustSave(t, s, storage.Link{ShortCode: "redirect2", LongURL: "https://www.example.com/default"})
+	if link, err := storage.Resolve(ctx, s, "redirect2"); err != nil || link.RedirectStatus != 0 {
+		t.Fatalf("Resolve: expected the server default, got %+v, %v", link, err)
+	}
+}
+
+func mustSave(t *testing.T, s storage.Storage, link storage.Link) {
+	ctx := context.Background()
+	t.Helper()
+	if err := s.Save(ctx, link); err != nil {
+		t.Fatalf("Save %s failed: %v", link.ShortCode, err)
+	}
+}
+
+func mustSaveAlias(t *testing.T, s storage.Storage, link storage.Link) {
+	ctx := context.Background()
+	t.Helper()
+	if err := s.SaveAlias(ctx, link); err != nil {
+		t.Fatalf("SaveAlias %s failed: %v", link.ShortCode, err)
+	}
+}
+
+func expectShortCode(t *testing.T, s storage.Storage, longURL, want string) {
+	ctx := context.Background()
+	t.Helper()
+	if got, err := s.GetShortCode(ctx, longURL); err != nil || got != want {
+		t.Fatalf("GetShortCode(%s): expected %s, got %s, %v", longURL, want, got, err)
+	}
+}
+
+func expectNoShortCode(t *testing.T, s storage.Storage, longURL string) {
+	ctx := context.Background()
+	t.Helper()
+	if got, err := s.GetShortCode(ctx, longURL); err != storage.ErrNotFound {
+		t.Fatalf("GetShortCode(%s): expected ErrNotFound, got %s, %v", longURL, got, err)
+	}
+}
+
+// listAll follows cursors to the last page and returns every code in order
+func listAll(t *testing.T, s storage.Storage, opts storage.ListOptions) []string {
+	ctx := context.Background()
+	t.Helper()
+	var codes []string
+	for i := 0; i < 1000; i++ {
+		page, err := s.List(ctx, opts)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		for _, link := range page.Links {
+			codes = append(codes, link.ShortCode)
+		}
+		if page.NextCursor == "" {
+			return codes
+		}
+		opts.Cursor = page.NextCursor
+	}
+	t.Fatal("Listing did not terminate")
+	return nil
+}
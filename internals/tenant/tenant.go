@@ -0,0 +1,96 @@
+// Package tenant describes the brands served by one deployment. Each tenant
+// has its own base URL, the hosts its redirects are served on, and its own
+// code space, so the same code or URL in two tenants never collide.
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+// tenant ids name storage directories and key prefixes, so keep them plain
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Tenant is one brand and the domains it owns
+type Tenant struct {
+	ID      string   `json:"id"`
+	BaseURL string   `json:"base_url"`        // short URLs are BaseURL/code
+	Hosts   []string `json:"hosts,omitempty"` // extra hosts serving redirects, besides BaseURL's
+}
+
+// AllHosts returns the normalized hosts serving t's redirects, BaseURL's first
+func (t Tenant) AllHosts() []string {
+	var hosts []string
+	if u, err := url.Parse(t.BaseURL); err == nil && u.Host != "" {
+		hosts = append(hosts, NormalizeHost(u.Host))
+	}
+	for _, host := range t.Hosts {
+		hosts = append(hosts, NormalizeHost(host))
+	}
+	return hosts
+}
+
+// NormalizeHost lowercases a Host header and drops its port, so
+// "Acme.link:443" matches a tenant configured as "acme.link"
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// Validate checks a tenant's id and base URL
+func (t Tenant) Validate() error {
+	if !idPattern.MatchString(t.ID) {
+		return fmt.Errorf("%w: id %q must be 1-32 lowercase letters, digits, '-' or '_'", ErrInvalidTenant, t.ID)
+	}
+	u, err := url.Parse(t.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: tenant %s: base_url must be an absolute http(s) URL", ErrInvalidTenant, t.ID)
+	}
+	if strings.HasSuffix(t.BaseURL, "/") {
+		return fmt.Errorf("%w: tenant %s: base_url must not end in '/'", ErrInvalidTenant, t.ID)
+	}
+	return nil
+}
+
+// LoadFile reads a JSON array of tenants, rejecting invalid ones and ids or
+// hosts claimed twice
+func LoadFile(path string) ([]Tenant, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("tenant: read file: %w", err)
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTenant, path, err)
+	}
+
+	ids := make(map[string]bool)
+	hosts := make(map[string]string)
+	for _, t := range tenants {
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		if ids[t.ID] {
+			return nil, fmt.Errorf("%w: tenant %s: id already in use", ErrInvalidTenant, t.ID)
+		}
+		ids[t.ID] = true
+		for _, host := range t.AllHosts() {
+			if owner, taken := hosts[host]; taken && owner != t.ID {
+				return nil, fmt.Errorf("%w: tenant %s: host %s already belongs to %s", ErrInvalidTenant, t.ID, host, owner)
+			}
+			hosts[host] = t.ID
+		}
+	}
+	return tenants, nil
+}
@@ -0,0 +1,142 @@
+/*
+This file contains unit tests for custom alias (vanity short code) support.
+
+- TestShortenURL_Alias_Success: POST /api/shorten with an alias returns a short URL ending in the alias, and the alias redirects.
+- TestShortenURL_Alias_Taken: an alias already mapped to another URL yields 409 Conflict with the alias_taken error code.
+- TestShortenURL_Alias_Idempotent: repeating the same alias for the same URL succeeds.
+- TestShortenURL_Alias_Invalid: aliases that are too short, too long, contain illegal characters or match reserved routes yield 400 with the invalid_alias error code.
+- TestService_Alias_KeepsHashPath: creating an alias does not change the code returned for the same URL without an alias.
+*/
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func postShorten(h *handler.Handler, reqBody handler.ShortenRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/api/shorten", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ShortenURL(w, req)
+	return w
+}
+
+func TestShortenURL_Alias_Success(t *testing.T) {
+	h := setupHandler()
+
+	w := postShorten(h, handler.ShortenRequest{URL: "https://www.example.com/sale", Alias: "spring-sale"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp handler.ShortenResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.ShortURL != "http://localhost:8080/spring-sale" {
+		t.Fatalf("Expected alias short URL, got %s", resp.ShortURL)
+	}
+
+	req := httptest.NewRequest("GET", "/spring-sale", nil)
+	rec := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/{shortCode}", h.RedirectURL)
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Expected status 302, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "https://www.example.com/sale" {
+		t.Fatalf("Expected redirect to alias target, got %s", location)
+	}
+}
+
+func TestShortenURL_Alias_Taken(t *testing.T) {
+	h := setupHandler()
+
+	postShorten(h, handler.ShortenRequest{URL: "https://www.example.com/a", Alias: "promo"})
+	w := postShorten(h, handler.ShortenRequest{URL: "https://www.example.com/b", Alias: "promo"})
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got %d", w.Code)
+	}
+
+	var errResp handler.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if errResp.Code != handler.CodeAliasTaken || errResp.Error == "" {
+		t.Fatalf("Expected structured alias_taken error, got %+v", errResp)
+	}
+}
+
+func TestShortenURL_Alias_Idempotent(t *testing.T) {
+	h := setupHandler()
+
+	reqBody := handler.ShortenRequest{URL: "https://www.example.com/a", Alias: "promo"}
+	postShorten(h, reqBody)
+	w := postShorten(h, reqBody)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for repeated alias, got %d", w.Code)
+	}
+}
+
+func TestShortenURL_Alias_Invalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		alias string
+	}{
+		{"Too short", "ab"},
+		{"Too long", strings.Repeat("a", service.MaxAliasLength+1)},
+		{"Slash", "spring/sale"},
+		{"Space", "spring sale"},
+		{"Reserved", "api"},
+		{"Reserved mixed case", "API"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := setupHandler()
+			w := postShorten(h, handler.ShortenRequest{URL: "https://www.example.com", Alias: tc.alias})
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", w.Code)
+			}
+			var errResp handler.ErrorResponse
+			json.NewDecoder(w.Body).Decode(&errResp)
+			if errResp.Code != handler.CodeInvalidAlias {
+				t.Fatalf("Expected invalid_alias code, got %q", errResp.Code)
+			}
+		})
+	}
+}
+
+func TestService_Alias_KeepsHashPath(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
+	longURL := "https://www.example.com/both"
+
+	if _, _, err := svc.ShortenURLWithAlias(context.Background(), longURL, "both-alias"); err != nil {
+		t.Fatalf("Alias shorten failed: %v", err)
+	}
+
+	_, code, err := svc.ShortenURL(context.Background(), longURL)
+	if err != nil {
+		t.Fatalf("Shorten failed: %v", err)
+	}
+	if code != svc.GenerateShortCode(longURL) {
+		t.Fatalf("Expected hash-derived code, got %s", code)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+This file contains unit tests for the click analytics subsystem.
+
+- TestAggregator_Counts: events are folded into total, GET/HEAD and per-day counts, with days in ascending order.
+- TestRecorder_DrainsOnClose: every event recorded before Close reaches the aggregator.
+- TestRecorder_RecordAfterClose: a Record racing or following Close is ignored instead of panicking.
+- TestRecorder_DropWarningRateLimited: a burst of dropped events is logged once, with the running total, through the injected logger.
+- TestLinkStats_AfterRedirects: GET and HEAD redirects through the handler are recorded, failed lookups are not, and GET /api/links/{shortCode}/stats reports them.
+- TestLinkStats_NotFound: stats for an unknown short code yield 404.
+*/
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/analytics"
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/logging"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func TestAggregator_Counts(t *testing.T) {
+	agg := analytics.NewAggregator()
+	day1 := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+
+	agg.Add(analytics.Event{Timestamp: day2, ShortCode: "abc", Method: "GET"})
+	agg.Add(analytics.Event{Timestamp: day1, ShortCode: "abc", Method: "GET"})
+	agg.Add(analytics.Event{Timestamp: day1, ShortCode: "abc", Method: "HEAD"})
+	agg.Add(analytics.Event{Timestamp: day1, ShortCode: "other", Method: "GET"})
+
+	stats := agg.Stats("abc")
+	if stats.Total != 3 || stats.Get != 2 || stats.Head != 1 {
+		t.Fatalf("Unexpected totals: %+v", stats)
+	}
+	if len(stats.Daily) != 2 {
+		t.Fatalf("Expected 2 days, got %d", len(stats.Daily))
+	}
+	if stats.Daily[0].Date != "2026-03-01" || stats.Daily[0].Clicks != 2 {
+		t.Fatalf("Unexpected first day: %+v", stats.Daily[0])
+	}
+	if stats.Daily[1].Date != "2026-03-02" || stats.Daily[1].Clicks != 1 {
+		t.Fatalf("Unexpected second day: %+v", stats.Daily[1])
+	}
+
+	if empty := agg.Stats("missing"); empty.Total != 0 || empty.Daily == nil {
+		t.Fatalf("Expected empty stats with non-nil days, got %+v", empty)
+	}
+}
+
+func TestRecorder_DrainsOnClose(t *testing.T) {
+	agg := analytics.NewAggregator()
+	rec := analytics.NewRecorder(agg, 1000, nil)
+
+	recorded := 0
+	for i := 0; i < 500; i++ {
+		if rec.Record(analytics.Event{Timestamp: time.Now(), ShortCode: "abc", Method: "GET"}) {
+			recorded++
+		}
+	}
+	rec.Close()
+
+	if got := agg.Stats("abc").Total; got != int64(recorded) {
+		t.Fatalf("Expected %d events after drain, got %d", recorded, got)
+	}
+	if uint64(500-recorded) != rec.Dropped() {
+		t.Fatalf("Expected dropped count %d, got %d", 500-recorded, rec.Dropped())
+	}
+}
+
+func TestRecorder_RecordAfterClose(t *testing.T) {
+	agg := analytics.NewAggregator()
+	rec := analytics.NewRecorder(agg, 16, nil)
+
+	// redirects still in flight when a forced shutdown closes the recorder
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				rec.Record(analytics.Event{Timestamp: time.Now(), ShortCode: "late", Method: "GET"})
+			}
+		}()
+	}
+	rec.Close()
+	wg.Wait()
+
+	total := agg.Stats("late").Total
+	if rec.Record(analytics.Event{Timestamp: time.Now(), ShortCode: "late", Method: "GET"}) {
+		t.Fatal("Expected Record after Close to report the event as not recorded")
+	}
+	if got := agg.Stats("late").Total; got != total {
+		t.Fatalf("Expected nothing aggregated after Close, got %d more", got-total)
+	}
+}
+
+func TestRecorder_DropWarningRateLimited(t *testing.T) {
+	var buf bytes.Buffer
+	rec := analytics.NewRecorder(analytics.NewAggregator(), 1, logging.New(&buf, logging.Options{Format: logging.FormatText}))
+
+	for i := 0; i < 10000; i++ {
+		rec.Record(analytics.Event{Timestamp: time.Now(), ShortCode: "abc", Method: "GET"})
+	}
+	rec.Close()
+
+	if rec.Dropped() == 0 {
+		t.Skip("the buffer never filled")
+	}
+	if got := strings.Count(buf.String(), "Record buffer full"); got != 1 {
+		t.Fatalf("Expected one drop warning for the burst, got %d: %s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), "component=analytics") || !strings.Contains(buf.String(), "dropped_total=") {
+		t.Fatalf("Expected the warning on the injected logger with the drop count, got %s", buf.String())
+	}
+}
+
+func TestLinkStats_AfterRedirects(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
+	rec := analytics.NewRecorder(analytics.NewAggregator(), 16, nil)
+	h := handler.NewHandler(svc, rec, nil)
+
+	_, code, err := svc.ShortenURL(context.Background(), "https://www.example.com/tracked")
+	if err != nil {
+		t.Fatalf("Shorten failed: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/links/{shortCode}/stats", h.LinkStats)
+	router.HandleFunc("/{shortCode}", h.RedirectURL)
+
+	for _, method := range []string{"GET", "GET", "HEAD"} {
+		req := httptest.NewRequest(method, "/"+code, nil)
+		req.Header.Set("Referer", "https://news.example.org/")
+		req.Header.Set("User-Agent", "test-agent")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("Expected status 302, got %d", w.Code)
+		}
+	}
+
+	// not found redirects are not clicks
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing1", nil))
+
+	rec.Close()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/"+code+"/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp handler.StatsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if resp.ShortCode != code || resp.Total != 3 || resp.Get != 2 || resp.Head != 1 {
+		t.Fatalf("Unexpected stats: %+v", resp)
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+	if len(resp.Daily) != 1 || resp.Daily[0].Date != today || resp.Daily[0].Clicks != 3 {
+		t.Fatalf("Unexpected daily counts: %+v", resp.Daily)
+	}
+}
+
+func TestLinkStats_NotFound(t *testing.T) {
+	h := setupHandler()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/links/{shortCode}/stats", h.LinkStats)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/nonexistent/stats", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", w.Code)
+	}
+}
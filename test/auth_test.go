@@ -0,0 +1,232 @@
+/*
+This file contains unit tests for API key authentication.
+
+- TestGenerateKey: generated keys are prefixed, unique and hash to the returned hash.
+- TestKeyStore_Validation: key records with no id, a malformed hash, no or unknown scopes, or a duplicate id or hash are rejected.
+- TestLoadKeyFile: a JSON key file is loaded and keys are looked up by hash, a malformed file is rejected.
+- TestAuth_MissingOrUnknownKey: /api routes answer 401 with a Bearer challenge without a key or with an unknown one.
+- TestAuth_Scopes: a create key can shorten but not list, a read key can list but not shorten, an admin key can do both and delete.
+- TestAuth_APIKeyHeader: the key is also accepted in the X-API-Key header.
+- TestAuth_PublicRoutes: redirects stay public while authentication is on.
+- TestAuth_Disabled: without a key store every /api route is open.
+*/
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/auth"
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// setupAuthRouter wires the /api routes as main does. The returned map holds,
+// per scope, a raw key granting only that scope.
+func setupAuthRouter(t *testing.T, withKeys bool) (*mux.Router, map[auth.Scope]string) {
+	h := handler.NewHandler(service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil), nil, nil)
+
+	raws := make(map[auth.Scope]string)
+	if withKeys {
+		store, _ := auth.NewMemoryKeyStore()
+		for _, scope := range []auth.Scope{auth.ScopeCreate, auth.ScopeRead, auth.ScopeAdmin} {
+			raw, hash, err := auth.GenerateKey()
+			if err != nil {
+				t.Fatalf("GenerateKey failed: %v", err)
+			}
+			if err := store.Add(auth.Key{ID: string(scope), Hash: hash, Scopes: []auth.Scope{scope}}); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			raws[scope] = raw
+		}
+		h.SetKeyStore(store)
+	}
+
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(h.Authenticate)
+	api.HandleFunc("/shorten", h.Require(auth.ScopeCreate, h.ShortenURL)).Methods("POST")
+	api.HandleFunc("/links", h.Require(auth.ScopeRead, h.ListLinks)).Methods("GET")
+	api.HandleFunc("/links/{shortCode}", h.Require(auth.ScopeAdmin, h.DeleteLink)).Methods("DELETE")
+	router.HandleFunc("/{shortCode}", h.RedirectURL).Methods("GET", "HEAD")
+	return router, raws
+}
+
+func serveWithKey(router *mux.Router, method, target, body, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func bearer(router *mux.Router, method, target, body, raw string) *httptest.ResponseRecorder {
+	return serveWithKey(router, method, target, body, "Authorization", "Bearer "+raw)
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	var resp handler.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	return resp.Code
+}
+
+func TestGenerateKey(t *testing.T) {
+	raw1, hash1, err := auth.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	raw2, _, _ := auth.GenerateKey()
+
+	if !strings.HasPrefix(raw1, "us_") {
+		t.Fatalf("Expected us_ prefix, got %s", raw1)
+	}
+	if raw1 == raw2 {
+		t.Fatal("Expected two generated keys to differ")
+	}
+	if auth.HashKey(raw1) != hash1 || len(hash1) != 64 {
+		t.Fatalf("Expected hash to be the hex SHA-256 of the key, got %s", hash1)
+	}
+}
+
+func TestKeyStore_Validation(t *testing.T) {
+	_, hash, _ := auth.GenerateKey()
+	_, other, _ := auth.GenerateKey()
+	create := []auth.Scope{auth.ScopeCreate}
+
+	for name, keys := range map[string][]auth.Key{
+		"no id":         {{Hash: hash, Scopes: create}},
+		"short hash":    {{ID: "a", Hash: "abc123", Scopes: create}},
+		"non-hex hash":  {{ID: "a", Hash: strings.Repeat("z", 64), Scopes: create}},
+		"no scopes":     {{ID: "a", Hash: hash}},
+		"unknown scope": {{ID: "a", Hash: hash, Scopes: []auth.Scope{"links:write"}}},
+		"duplicate id":  {{ID: "a", Hash: hash, Scopes: create}, {ID: "a", Hash: other, Scopes: create}},
+		"duplicate hash": {{ID: "a", Hash: hash, Scopes: create},
+			{ID: "b", Hash: strings.ToUpper(hash), Scopes: create}},
+	} {
+		if _, err := auth.NewMemoryKeyStore(keys...); !errors.Is(err, auth.ErrInvalidKey) {
+			t.Errorf("%s: expected ErrInvalidKey, got %v", name, err)
+		}
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	raw, hash, _ := auth.GenerateKey()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(path, []byte(`[{"id":"ci","hash":"`+hash+`","scopes":["links:create","links:read"]}]`), 0600)
+
+	store, err := auth.LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyFile failed: %v", err)
+	}
+	key, err := store.Lookup(context.Background(), auth.HashKey(raw))
+	if err != nil || key.ID != "ci" || !key.Allows(auth.ScopeRead) || key.Allows(auth.ScopeAdmin) {
+		t.Fatalf("Unexpected key %+v, err %v", key, err)
+	}
+	if _, err := store.Lookup(context.Background(), auth.HashKey("us_unknown")); err != auth.ErrKeyNotFound {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	os.WriteFile(path, []byte(`{"id":"ci"}`), 0600)
+	if _, err := auth.LoadKeyFile(path); !errors.Is(err, auth.ErrInvalidKey) {
+		t.Fatalf("Expected ErrInvalidKey for a malformed file, got %v", err)
+	}
+}
+
+func TestAuth_MissingOrUnknownKey(t *testing.T) {
+	router, _ := setupAuthRouter(t, true)
+
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"missing":    serveWithKey(router, "GET", "/api/links", "", "", ""),
+		"unknown":    bearer(router, "GET", "/api/links", "", "us_not-a-real-key"),
+		"bad scheme": serveWithKey(router, "GET", "/api/links", "", "Authorization", "Basic dXNlcjpwYXNz"),
+	} {
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d", name, w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("%s: expected a WWW-Authenticate challenge", name)
+		}
+		if code := errorCode(t, w); code != handler.CodeUnauthorized {
+			t.Fatalf("%s: expected code %s, got %s", name, handler.CodeUnauthorized, code)
+		}
+	}
+}
+
+func TestAuth_Scopes(t *testing.T) {
+	router, keys := setupAuthRouter(t, true)
+	body := `{"url":"https://www.example.com/scoped","alias":"scoped"}`
+
+	if w := bearer(router, "POST", "/api/shorten", body, keys[auth.ScopeCreate]); w.Code != http.StatusOK {
+		t.Fatalf("Expected create key to shorten, got %d", w.Code)
+	}
+	w := bearer(router, "GET", "/api/links", "", keys[auth.ScopeCreate])
+	if w.Code != http.StatusForbidden || errorCode(t, w) != handler.CodeForbidden {
+		t.Fatalf("Expected 403 forbidden listing with a create key, got %d", w.Code)
+	}
+
+	if w := bearer(router, "GET", "/api/links", "", keys[auth.ScopeRead]); w.Code != http.StatusOK {
+		t.Fatalf("Expected read key to list, got %d", w.Code)
+	}
+	if w := bearer(router, "POST", "/api/shorten", body, keys[auth.ScopeRead]); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 shortening with a read key, got %d", w.Code)
+	}
+	if w := bearer(router, "DELETE", "/api/links/scoped", "", keys[auth.ScopeRead]); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 deleting with a read key, got %d", w.Code)
+	}
+
+	admin := keys[auth.ScopeAdmin]
+	if w := bearer(router, "POST", "/api/shorten", body, admin); w.Code != http.StatusOK {
+		t.Fatalf("Expected admin key to shorten, got %d", w.Code)
+	}
+	if w := bearer(router, "GET", "/api/links", "", admin); w.Code != http.StatusOK {
+		t.Fatalf("Expected admin key to list, got %d", w.Code)
+	}
+	if w := bearer(router, "DELETE", "/api/links/scoped", "", admin); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected admin key to delete, got %d", w.Code)
+	}
+}
+
+func TestAuth_APIKeyHeader(t *testing.T) {
+	router, keys := setupAuthRouter(t, true)
+
+	if w := serveWithKey(router, "GET", "/api/links", "", "X-API-Key", keys[auth.ScopeRead]); w.Code != http.StatusOK {
+		t.Fatalf("Expected X-API-Key to authenticate, got %d", w.Code)
+	}
+}
+
+func TestAuth_PublicRoutes(t *testing.T) {
+	router, keys := setupAuthRouter(t, true)
+	bearer(router, "POST", "/api/shorten", `{"url":"https://www.example.com/public","alias":"public"}`, keys[auth.ScopeCreate])
+
+	if w := serveWithKey(router, "GET", "/public", "", "", ""); w.Code != http.StatusFound {
+		t.Fatalf("Expected redirect without a key, got %d", w.Code)
+	}
+}
+
+func TestAuth_Disabled(t *testing.T) {
+	router, _ := setupAuthRouter(t, false)
+
+	if w := serveWithKey(router, "POST", "/api/shorten", `{"url":"https://www.example.com/open"}`, "", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected shorten without a key store, got %d", w.Code)
+	}
+	if w := serveWithKey(router, "GET", "/api/links", "", "", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected list without a key store, got %d", w.Code)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+This file contains unit tests for the bulk shortening endpoint.
+
+- TestShortenBatch_JSON: a JSON array yields one result per item in input order, with per-item error codes for invalid and missing URLs alongside successful items.
+- TestShortenBatch_Idempotent: duplicate URLs within a batch share a short URL.
+- TestShortenBatch_TooLarge: a batch over the configured maximum yields 413 with the batch_too_large code.
+- TestShortenBatch_TooManyBytes: a JSON array within the item limit but over its byte limit yields 413 with the batch_too_large code.
+- TestShortenBatch_NotArray: a body that is not a JSON array yields 400.
+- TestShortenBatch_NDJSON: an NDJSON body is answered line by line, with malformed lines reported as invalid_item and an oversized stream terminated by a batch_too_large line.
+*/
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+)
+
+func postBatch(h *handler.Handler, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/shorten/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	h.ShortenBatch(w, req)
+	return w
+}
+
+func TestShortenBatch_JSON(t *testing.T) {
+	h := setupHandler()
+	items := []handler.ShortenRequest{
+		{URL: "https://www.example.com/1"},
+		{URL: "not-a-url"},
+		{URL: ""},
+		{URL: "https://www.example.com/4", Alias: "batch-alias"},
+	}
+	body, _ := json.Marshal(items)
+
+	w := postBatch(h, "application/json", string(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp handler.BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Results) != len(items) {
+		t.Fatalf("Expected %d results, got %d", len(items), len(resp.Results))
+	}
+	for i, result := range resp.Results {
+		if result.Index != i {
+			t.Fatalf("Expected result %d to carry index %d, got %d", i, i, result.Index)
+		}
+	}
+
+	if resp.Results[0].ShortURL == "" || resp.Results[0].Error != "" {
+		t.Fatalf("Expected first item to succeed, got %+v", resp.Results[0])
+	}
+	if resp.Results[1].Code != handler.CodeInvalidURL {
+		t.Fatalf("Expected invalid_url for second item, got %+v", resp.Results[1])
+	}
+	if resp.Results[2].Code != handler.CodeURLRequired {
+		t.Fatalf("Expected url_required for third item, got %+v", resp.Results[2])
+	}
+	if resp.Results[3].ShortURL != "http://localhost:8080/batch-alias" {
+		t.Fatalf("Expected alias short URL for fourth item, got %+v", resp.Results[3])
+	}
+}
+
+func TestShortenBatch_Idempotent(t *testing.T) {
+	h := setupHandler()
+
+	w := postBatch(h, "application/json", `[{"url":"https://www.example.com/dup"},{"url":"https://www.example.com/dup"}]`)
+
+	var resp handler.BatchResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Results) != 2 || resp.Results[0].ShortURL != resp.Results[1].ShortURL {
+		t.Fatalf("Expected duplicate URLs to share a short URL, got %+v", resp.Results)
+	}
+}
+
+func TestShortenBatch_TooLarge(t *testing.T) {
+	h := setupHandler()
+	h.SetMaxBatchSize(2)
+
+	w := postBatch(h, "application/json", `[{"url":"https://a.example.com"},{"url":"https://b.example.com"},{"url":"https://c.example.com"}]`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413, got %d", w.Code)
+	}
+
+	var errResp handler.ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Code != handler.CodeBatchTooLarge {
+		t.Fatalf("Expected batch_too_large code, got %q", errResp.Code)
+	}
+}
+
+func TestShortenBatch_TooManyBytes(t *testing.T) {
+	h := setupHandler()
+	h.SetMaxBatchSize(1)
+
+	// one item, but far more bytes than a single item is allowed
+	long := "https://example.com/" + strings.Repeat("a", 200*1024)
+	w := postBatch(h, "application/json", `[{"url":"`+long+`"}]`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var errResp handler.ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Code != handler.CodeBatchTooLarge || !strings.Contains(errResp.Error, "bytes") {
+		t.Fatalf("Expected batch_too_large over the byte limit, got %+v", errResp)
+	}
+}
+
+func TestShortenBatch_NotArray(t *testing.T) {
+	h := setupHandler()
+
+	for _, body := range []string{`{"url":"https://example.com"}`, `not json`, `[{"url":"https://example.com"}`} {
+		w := postBatch(h, "application/json", body)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for %q, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestShortenBatch_NDJSON(t *testing.T) {
+	h := setupHandler()
+	h.SetMaxBatchSize(3)
+
+	var body bytes.Buffer
+	body.WriteString(`{"url":"https://www.example.com/1"}` + "\n")
+	body.WriteString("\n")
+	body.WriteString(`{broken` + "\n")
+	body.WriteString(`{"url":"https://www.example.com/3"}` + "\n")
+	body.WriteString(`{"url":"https://www.example.com/4"}` + "\n")
+
+	w := postBatch(h, "application/x-ndjson", body.String())
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("Expected NDJSON response, got %s", ct)
+	}
+
+	var results []handler.BatchResult
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var result handler.BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to decode line %q: %v", scanner.Text(), err)
+		}
+		results = append(results, result)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 result lines, got %d: %+v", len(results), results)
+	}
+	if results[0].ShortURL == "" {
+		t.Fatalf("Expected first line to succeed, got %+v", results[0])
+	}
+	if results[1].Index != 1 || results[1].Code != handler.CodeInvalidItem {
+		t.Fatalf("Expected invalid_item for malformed line, got %+v", results[1])
+	}
+	if results[2].Index != 2 || results[2].ShortURL == "" {
+		t.Fatalf("Expected third line to succeed, got %+v", results[2])
+	}
+	if results[3].Code != handler.CodeBatchTooLarge {
+		t.Fatalf("Expected stream to end with batch_too_large, got %+v", results[3])
+	}
+}
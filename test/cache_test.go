@@ -0,0 +1,167 @@
+/*
+This file contains unit tests for the read-through cache decorator.
+
+- TestCachedStorage_HitsAndMisses: the first lookup of a code goes to the wrapped storage and later ones are answered from the cache.
+- TestCachedStorage_NegativeTTL: unknown codes are remembered for the negative TTL, then looked up again.
+- TestCachedStorage_Invalidation: Save, Update and Delete through the cache drop the cached answer for that code.
+- TestCachedStorage_LRUEviction: the cache stays within its size by evicting the least recently used code.
+- TestCachedStorage_Expiry: a cached link stops redirecting once its own expiry passes.
+- TestCachedStorage_TTL: a cached hit is re-read after the TTL, picking up writes made behind the cache.
+*/
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+// countingStorage counts GetLink calls reaching the wrapped storage
+type countingStorage struct {
+	storage.Storage
+	reads int
+}
+
+func (c *countingStorage) GetLink(ctx context.Context, shortCode string) (storage.Link, error) {
+	c.reads++
+	return c.Storage.GetLink(ctx, shortCode)
+}
+
+func newCountedCache(opts storage.CacheOptions) (*storage.CachedStorage, *countingStorage) {
+	backend := &countingStorage{Storage: storage.NewMemoryStorage(nil)}
+	return storage.NewCachedStorage(backend, opts), backend
+}
+
+func TestCachedStorage_HitsAndMisses(t *testing.T) {
+	ctx := context.Background()
+	cache, backend := newCountedCache(storage.DefaultCacheOptions())
+	cache.Save(ctx, storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/a"})
+
+	for i := 0; i < 3; i++ {
+		longURL, err := cache.GetLongURL(ctx, "abc12345")
+		if err != nil || longURL != "https://www.example.com/a" {
+			t.Fatalf("Expected cached URL, got %s, %v", longURL, err)
+		}
+	}
+
+	if backend.reads != 1 {
+		t.Fatalf("Expected one read of the wrapped storage, got %d", backend.reads)
+	}
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Fatalf("Expected 2 hits, 1 miss and 1 entry, got %+v", stats)
+	}
+}
+
+func TestCachedStorage_NegativeTTL(t *testing.T) {
+	ctx := context.Background()
+	opts := storage.DefaultCacheOptions()
+	opts.NegativeTTL = 50 * time.Millisecond
+	cache, backend := newCountedCache(opts)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetLongURL(ctx, "missing1"); err != storage.ErrNotFound {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if backend.reads != 1 {
+		t.Fatalf("Expected unknown code to be remembered, got %d reads", backend.reads)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	cache.GetLongURL(ctx, "missing1")
+	if backend.reads != 2 {
+		t.Fatalf("Expected lookup after the negative TTL, got %d reads", backend.reads)
+	}
+}
+
+func TestCachedStorage_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newCountedCache(storage.DefaultCacheOptions())
+
+	// a remembered miss must not hide a newly saved link
+	cache.GetLongURL(ctx, "abc12345")
+	cache.Save(ctx, storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/a"})
+	if longURL, err := cache.GetLongURL(ctx, "abc12345"); err != nil || longURL != "https://www.example.com/a" {
+		t.Fatalf("Expected saved link after negative entry, got %s, %v", longURL, err)
+	}
+
+	cache.Update(ctx, "abc12345", "https://www.example.com/b")
+	if longURL, _ := cache.GetLongURL(ctx, "abc12345"); longURL != "https://www.example.com/b" {
+		t.Fatalf("Expected updated destination, got %s", longURL)
+	}
+
+	cache.Delete(ctx, "abc12345")
+	if _, err := cache.GetLongURL(ctx, "abc12345"); err != storage.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestCachedStorage_LRUEviction(t *testing.T) {
+	ctx := context.Background()
+	opts := storage.DefaultCacheOptions()
+	opts.Size = 2
+	cache, backend := newCountedCache(opts)
+
+	for _, code := range []string{"code0001", "code0002", "code0003"} {
+		cache.Save(ctx, storage.Link{ShortCode: code, LongURL: "https://www.example.com/" + code})
+	}
+
+	cache.GetLongURL(ctx, "code0001")
+	cache.GetLongURL(ctx, "code0002")
+	cache.GetLongURL(ctx, "code0001") // code0002 is now least recently used
+	cache.GetLongURL(ctx, "code0003") // evicts code0002
+	reads := backend.reads
+
+	cache.GetLongURL(ctx, "code0001")
+	if backend.reads != reads {
+		t.Fatalf("Expected recently used code to stay cached")
+	}
+	cache.GetLongURL(ctx, "code0002")
+	if backend.reads != reads+1 {
+		t.Fatalf("Expected least recently used code to be evicted")
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Evictions != 2 {
+		t.Fatalf("Expected 2 entries and 2 evictions, got %+v", stats)
+	}
+}
+
+func TestCachedStorage_Expiry(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newCountedCache(storage.DefaultCacheOptions())
+	cache.Save(ctx, storage.Link{ShortCode: "short001", LongURL: "https://www.example.com/short", ExpiresAt: time.Now().Add(50 * time.Millisecond)})
+
+	if _, err := cache.GetLongURL(ctx, "short001"); err != nil {
+		t.Fatalf("Expected live link, got %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := cache.GetLongURL(ctx, "short001"); err != storage.ErrExpired {
+		t.Fatalf("Expected ErrExpired from the cache, got %v", err)
+	}
+}
+
+func TestCachedStorage_TTL(t *testing.T) {
+	ctx := context.Background()
+	backend := storage.NewMemoryStorage(nil)
+	opts := storage.DefaultCacheOptions()
+	opts.TTL = 50 * time.Millisecond
+	cache := storage.NewCachedStorage(backend, opts)
+
+	backend.Save(ctx, storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/a"})
+	cache.GetLongURL(ctx, "abc12345")
+
+	// written behind the cache, as another replica would
+	backend.Update(ctx, "abc12345", "https://www.example.com/b")
+	if longURL, _ := cache.GetLongURL(ctx, "abc12345"); longURL != "https://www.example.com/a" {
+		t.Fatalf("Expected cached destination within the TTL, got %s", longURL)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if longURL, _ := cache.GetLongURL(ctx, "abc12345"); longURL != "https://www.example.com/b" {
+		t.Fatalf("Expected fresh destination after the TTL, got %s", longURL)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+This file contains unit tests for URL canonicalization.
+
+- TestNormalizeURL_Default: scheme and host case, default ports, empty paths, dot segments and IDN hosts are normalized while query order and tracking parameters are kept, and hosts outside strict DNS rules such as "my_host" are still accepted.
+- TestNormalizeURL_QueryOptions: SortQuery orders parameters by key and StripTracking drops utm_* and click-id parameters.
+- TestNormalizeURL_Disabled: with normalization off the URL is returned untouched.
+- TestService_Canonical_SharesCode: equivalent spellings of a URL share one short code and redirect to the canonical form.
+- TestShortenURL_Canonical_EchoesInput: the handler echoes the submitted URL in long_url rather than the canonical one.
+*/
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+func TestNormalizeURL_Default(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want string
+	}{
+		{"HTTPS://Example.COM", "https://example.com/"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"http://example.com:8080/a", "http://example.com:8080/a"},
+		{"https://example.com/a/./b/../c", "https://example.com/a/c"},
+		{"https://example.com/a/b/..", "https://example.com/a/"},
+		{"https://example.com/../../a", "https://example.com/a"},
+		{"https://bücher.example/", "https://xn--bcher-kva.example/"},
+		{"https://my_host.example.com/a", "https://my_host.example.com/a"},
+		{"https://My_Host.Bücher.example/a", "https://my_host.xn--bcher-kva.example/a"},
+		{"https://[::1]:443/", "https://[::1]/"},
+		{"https://example.com/p%2Fq?b=2&a=1&utm_source=x#Frag", "https://example.com/p%2Fq?b=2&a=1&utm_source=x#Frag"},
+	}
+
+	for _, tc := range testCases {
+		got, err := service.NormalizeURL(tc.in, service.DefaultNormalizeOptions())
+		if err != nil {
+			t.Fatalf("NormalizeURL(%q) failed: %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Fatalf("NormalizeURL(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeURL_QueryOptions(t *testing.T) {
+	in := "https://example.com/p?z=1&utm_source=news&a=2&fbclid=abc&a=1&UTM_Medium=mail"
+
+	sorted, _ := service.NormalizeURL(in, service.NormalizeOptions{Enabled: true, SortQuery: true})
+	if sorted != "https://example.com/p?UTM_Medium=mail&a=2&a=1&fbclid=abc&utm_source=news&z=1" {
+		t.Fatalf("Unexpected sorted URL: %s", sorted)
+	}
+
+	stripped, _ := service.NormalizeURL(in, service.NormalizeOptions{Enabled: true, StripTracking: true})
+	if stripped != "https://example.com/p?z=1&a=2&a=1" {
+		t.Fatalf("Unexpected stripped URL: %s", stripped)
+	}
+
+	only, _ := service.NormalizeURL("https://example.com/?utm_source=x", service.NormalizeOptions{Enabled: true, StripTracking: true})
+	if only != "https://example.com/" {
+		t.Fatalf("Expected query to be dropped entirely, got %s", only)
+	}
+}
+
+func TestNormalizeURL_Disabled(t *testing.T) {
+	in := "HTTPS://Example.COM:443/a/../b"
+	got, _ := service.NormalizeURL(in, service.NormalizeOptions{})
+	if got != in {
+		t.Fatalf("Expected untouched URL, got %s", got)
+	}
+}
+
+func TestService_Canonical_SharesCode(t *testing.T) {
+	svc := service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil)
+	svc.SetNormalizeOptions(service.NormalizeOptions{Enabled: true, SortQuery: true, StripTracking: true})
+
+	variants := []string{
+		"https://example.com/page?a=1&b=2",
+		"https://Example.com:443/page?b=2&a=1",
+		"HTTPS://EXAMPLE.COM/x/../page?a=1&utm_campaign=spring&b=2",
+	}
+
+	var first string
+	for _, variant := range variants {
+		_, code, err := svc.ShortenURL(context.Background(), variant)
+		if err != nil {
+			t.Fatalf("Shorten(%q) failed: %v", variant, err)
+		}
+		if first == "" {
+			first = code
+		} else if code != first {
+			t.Fatalf("Expected %q to share code %s, got %s", variant, first, code)
+		}
+	}
+
+	longURL, _ := svc.GetLongURL(context.Background(), first)
+	if longURL != "https://example.com/page?a=1&b=2" {
+		t.Fatalf("Expected canonical destination, got %s", longURL)
+	}
+}
+
+func TestShortenURL_Canonical_EchoesInput(t *testing.T) {
+	h := setupHandler()
+
+	w := postShorten(h, handler.ShortenRequest{URL: "https://Example.com"})
+	var resp handler.ShortenResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+
+	if resp.LongURL != "https://Example.com" {
+		t.Fatalf("Expected submitted URL to be echoed, got %s", resp.LongURL)
+	}
+}
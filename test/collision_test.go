@@ -0,0 +1,132 @@
+/*
+This file contains unit tests for short code collision handling.
+
+- TestMemoryStorage_SaveCollision: saving a short code that already maps to a different URL returns ErrAlreadyExists and leaves both maps untouched.
+- TestMemoryStorage_SaveSameMapping: re-saving an identical mapping is not treated as a collision.
+- TestService_ShortenURL_ResolvesCollision: a generator that collides on its first attempt gets a fresh code for the second URL while the first link keeps its destination.
+- TestService_ShortenURL_CollisionDeterministic: collision resolution picks the same code on an independent store that saw the same writes.
+- TestService_ShortenURL_CodeSpaceExhausted: a generator that always collides yields ErrCodeSpaceExhausted instead of overwriting.
+*/
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+// collidingGenerator returns the same code for every URL on the first attempt
+func collidingGenerator(longURL string, attempt int) string {
+	if attempt == 0 {
+		return "collide0"
+	}
+	return service.HashShortCode(longURL, attempt)
+}
+
+func TestMemoryStorage_SaveCollision(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("First save failed: %v", err)
+	}
+
+	err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/b"})
+	if err != storage.ErrAlreadyExists {
+		t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	longURL, _ := store.GetLongURL(context.Background(), "samecode")
+	if longURL != "https://www.example.com/a" {
+		t.Fatalf("Expected original mapping to survive, got %s", longURL)
+	}
+
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/b"); err != storage.ErrNotFound {
+		t.Fatalf("Expected no reverse mapping for rejected URL, got %v", err)
+	}
+}
+
+func TestMemoryStorage_SaveSameMapping(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("First save failed: %v", err)
+	}
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("Expected identical save to succeed, got %v", err)
+	}
+}
+
+func TestService_ShortenURL_ResolvesCollision(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", service.CodeGeneratorFunc(collidingGenerator), nil)
+
+	_, code1, err := svc.ShortenURL(context.Background(), "https://www.example.com/first")
+	if err != nil {
+		t.Fatalf("First shorten failed: %v", err)
+	}
+	_, code2, err := svc.ShortenURL(context.Background(), "https://www.example.com/second")
+	if err != nil {
+		t.Fatalf("Second shorten failed: %v", err)
+	}
+
+	if code1 != "collide0" {
+		t.Fatalf("Expected first URL to get the first candidate, got %s", code1)
+	}
+	if code2 == code1 {
+		t.Fatalf("Expected a different code after collision, got %s for both", code2)
+	}
+
+	long1, _ := svc.GetLongURL(context.Background(), code1)
+	long2, _ := svc.GetLongURL(context.Background(), code2)
+	if long1 != "https://www.example.com/first" || long2 != "https://www.example.com/second" {
+		t.Fatalf("Expected both links to keep their destinations, got %s and %s", long1, long2)
+	}
+
+	// idempotency still holds for the URL that was re-derived
+	_, again, err := svc.ShortenURL(context.Background(), "https://www.example.com/second")
+	if err != nil || again != code2 {
+		t.Fatalf("Expected idempotent code %s, got %s, %v", code2, again, err)
+	}
+}
+
+func TestService_ShortenURL_CollisionDeterministic(t *testing.T) {
+	var codes []string
+	for i := 0; i < 2; i++ {
+		svc := service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", service.CodeGeneratorFunc(collidingGenerator), nil)
+		svc.ShortenURL(context.Background(), "https://www.example.com/first")
+		_, code, err := svc.ShortenURL(context.Background(), "https://www.example.com/second")
+		if err != nil {
+			t.Fatalf("Shorten failed: %v", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if codes[0] != codes[1] {
+		t.Fatalf("Expected deterministic collision resolution, got %s and %s", codes[0], codes[1])
+	}
+}
+
+func TestService_ShortenURL_CodeSpaceExhausted(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	always := func(longURL string, attempt int) string { return "fullcode" }
+	svc := service.NewURLService(store, "http://localhost:8080", service.CodeGeneratorFunc(always), nil)
+
+	if _, _, err := svc.ShortenURL(context.Background(), "https://www.example.com/first"); err != nil {
+		t.Fatalf("First shorten failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, _, err := svc.ShortenURL(context.Background(), fmt.Sprintf("https://www.example.com/other/%d", i))
+		if err != service.ErrCodeSpaceExhausted {
+			t.Fatalf("Expected ErrCodeSpaceExhausted, got %v", err)
+		}
+	}
+
+	longURL, _ := svc.GetLongURL(context.Background(), "fullcode")
+	if longURL != "https://www.example.com/first" {
+		t.Fatalf("Expected original mapping to survive, got %s", longURL)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+This file runs the storage conformance suite against every storage backend.
+
+- TestConformance_MemoryStorage: the in-memory maps.
+- TestConformance_ShardedMemoryStorage: the sharded in-memory maps, with few enough shards that codes share them.
+- TestConformance_FileStorage: the write-ahead log backend in a temporary directory.
+- TestConformance_SQLiteStorage: the SQLite backend on a temporary database file.
+- TestConformance_RedisStorage: the Redis backend against an in-process miniredis server.
+- TestConformance_CachedStorage: the read-through cache wrapped around the in-memory maps.
+*/
+package test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+	"URL_Shortener_Ruckus_Networks/internals/storage/storagetest"
+)
+
+func TestConformance_MemoryStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return storage.NewMemoryStorage(nil)
+	})
+}
+
+func TestConformance_ShardedMemoryStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return storage.NewShardedMemoryStorage(4, nil)
+	})
+}
+
+func TestConformance_FileStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		store := openFileStorage(t, t.TempDir(), storage.DefaultFileOptions())
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+func TestConformance_SQLiteStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		store := openSQLiteStorage(t, filepath.Join(t.TempDir(), "links.db"))
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+func TestConformance_RedisStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		opts := storage.DefaultRedisOptions()
+		opts.Addr = miniredis.RunT(t).Addr()
+		opts.ExpiredRetention = time.Hour
+
+		store, err := storage.NewRedisStorage(opts)
+		if err != nil {
+			t.Fatalf("Failed to open redis storage: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+func TestConformance_CachedStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return storage.NewCachedStorage(storage.NewMemoryStorage(nil), storage.DefaultCacheOptions())
+	})
+}
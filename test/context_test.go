@@ -0,0 +1,213 @@
+/*
+This file contains unit tests for request cancellation and deadlines.
+
+- TestService_CanceledContext: a cancelled context fails shortening with context.Canceled and stores nothing.
+- TestRedirectURL_DeadlineExceeded: a storage call still running at the request deadline answers 504 with the timeout code.
+- TestShortenURL_ClientDisconnected: a request abandoned by its client is dropped without writing a response.
+- TestGetLink_DeadlineExceeded: the link management routes answer 504 past the deadline instead of 404 or 500.
+- TestShortenBatch_DeadlineExceeded: a JSON batch past its deadline answers 504, a streamed batch ends with a timeout line.
+- TestStreams_OutlastWriteTimeout: on a real server, an export and an NDJSON batch taking longer than the server's WriteTimeout are delivered whole, and a batch body larger than one read that keeps arriving past ReadTimeout is processed to the end.
+*/
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// blockingStorage - lookups hang until the caller's context is done
+type blockingStorage struct {
+	storage.Storage
+}
+
+func (b blockingStorage) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (b blockingStorage) GetLink(ctx context.Context, shortCode string) (storage.Link, error) {
+	<-ctx.Done()
+	return storage.Link{}, ctx.Err()
+}
+
+func setupTimeoutRouter(timeout time.Duration) *mux.Router {
+	store := blockingStorage{storage.NewMemoryStorage(nil)}
+	h := handler.NewHandler(service.NewURLService(store, "http://localhost:8080", nil, nil), nil, nil)
+
+	r := mux.NewRouter()
+	r.Use(handler.Timeout(timeout))
+	r.HandleFunc("/api/links/{shortCode}", h.GetLink).Methods("GET")
+	r.HandleFunc("/{shortCode}", h.RedirectURL).Methods("GET")
+	return r
+}
+
+func TestService_CanceledContext(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := svc.ShortenURL(ctx, "https://www.example.com/cancel"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if page, _ := store.List(context.Background(), storage.ListOptions{}); len(page.Links) != 0 {
+		t.Fatalf("Expected nothing stored, got %+v", page.Links)
+	}
+}
+
+func TestRedirectURL_DeadlineExceeded(t *testing.T) {
+	r := setupTimeoutRouter(10 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/abc12345", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status 504, got %d", w.Code)
+	}
+	var resp handler.ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != handler.CodeTimeout {
+		t.Fatalf("Expected code %s, got %+v", handler.CodeTimeout, resp)
+	}
+}
+
+func TestShortenURL_ClientDisconnected(t *testing.T) {
+	h := setupHandler()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://www.example.com/gone"}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	h.ShortenURL(w, req)
+
+	if w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Fatalf("Expected no response for a disconnected client, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetLink_DeadlineExceeded(t *testing.T) {
+	r := setupTimeoutRouter(10 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/abc12345", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status 504, got %d", w.Code)
+	}
+}
+
+func TestShortenBatch_DeadlineExceeded(t *testing.T) {
+	h := setupHandler()
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	req := httptest.NewRequest("POST", "/api/shorten/batch", strings.NewReader(`[{"url":"https://www.example.com/a"}]`)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ShortenBatch(w, req)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status 504, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/shorten/batch", strings.NewReader("{\"url\":\"https://www.example.com/a\"}\n")).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w = httptest.NewRecorder()
+	h.ShortenBatch(w, req)
+
+	var result handler.BatchResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil || result.Code != handler.CodeTimeout {
+		t.Fatalf("Expected a timeout line, got %+v, %v", result, err)
+	}
+}
+
+// slowStorage - every save and listing takes delay
+type slowStorage struct {
+	storage.Storage
+	delay time.Duration
+}
+
+func (s slowStorage) Save(ctx context.Context, link storage.Link) error {
+	time.Sleep(s.delay)
+	return s.Storage.Save(ctx, link)
+}
+
+func (s slowStorage) List(ctx context.Context, opts storage.ListOptions) (storage.ListPage, error) {
+	time.Sleep(s.delay)
+	return s.Storage.List(ctx, opts)
+}
+
+func TestStreams_OutlastWriteTimeout(t *testing.T) {
+	store := &slowStorage{storage.NewMemoryStorage(nil), 20 * time.Millisecond}
+	h := handler.NewHandler(service.NewURLService(store, "http://localhost:8080", nil, nil), nil, nil)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/shorten/batch", h.ShortenBatch).Methods("POST")
+	r.HandleFunc("/api/admin/export", h.ExportLinks).Methods("GET")
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	// 20 saves at 20ms each take four times the write timeout, and the body
+	// is larger than the scanner reads before the first result goes out and
+	// keeps arriving past the read timeout
+	body, client := io.Pipe()
+	go func() {
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(client, "{\"url\":\"https://www.example.com/%d/%s\"}\n", i, strings.Repeat("x", 500))
+			time.Sleep(10 * time.Millisecond)
+		}
+		client.Close()
+	}()
+	resp, err := http.Post(srv.URL+"/api/shorten/batch", "application/x-ndjson", body)
+	if err != nil {
+		t.Fatalf("Batch request failed: %v", err)
+	}
+	if lines := countLines(t, resp); lines != 20 {
+		t.Fatalf("Expected 20 batch results, got %d", lines)
+	}
+
+	// a listing slower than the write timeout before the first line is out
+	store.delay = 150 * time.Millisecond
+	resp, err = http.Get(srv.URL + "/api/admin/export")
+	if err != nil {
+		t.Fatalf("Export request failed: %v", err)
+	}
+	if lines := countLines(t, resp); lines != 20 {
+		t.Fatalf("Expected 20 exported links, got %d", lines)
+	}
+}
+
+// countLines reads resp to the end and returns how many lines it held
+func countLines(t *testing.T, resp *http.Response) int {
+	t.Helper()
+	defer resp.Body.Close()
+	lines := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Response cut short after %d lines: %v", lines, err)
+	}
+	return lines
+}
@@ -0,0 +1,168 @@
+/*
+This file contains unit tests for serving links on several short domains.
+
+- TestShortenURL_Domain: a request with an allowed domain gets a short URL on that domain with the base URL's scheme, a request without one gets the base URL.
+- TestShortenURL_DomainNotAllowed: a domain outside the allow-list yields 400 with the invalid_domain error code.
+- TestRedirect_PinnedDomain: a link shortened for a domain only redirects on that domain, while a link shortened without one redirects on every host.
+- TestShorten_DomainUnion: shortening the same URL for another domain keeps the code and publishes it on both domains, moving the expiry without dropping either.
+- TestRedirect_PinnedDomainCached: the cache remembers a link's domains, so a cached pinned link still 404s on other hosts.
+- TestGetLink_Domains: link metadata lists the domains and builds the short URL on the first one.
+*/
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// setupDomainsRouter serves https://sho.rt with go.sho.rt and Links.Example as extra domains
+func setupDomainsRouter(t *testing.T, store storage.Storage) (*mux.Router, *handler.Handler, *service.URLService) {
+	svc := service.NewURLService(store, "https://sho.rt", nil, nil)
+	if err := svc.SetDomains([]string{"go.sho.rt", "Links.Example"}); err != nil {
+		t.Fatalf("SetDomains failed: %v", err)
+	}
+	h := handler.NewHandler(svc, nil, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/links/{shortCode}", h.GetLink).Methods("GET")
+	router.HandleFunc("/{shortCode}", h.RedirectURL).Methods("GET", "HEAD")
+	return router, h, svc
+}
+
+// shortenOn shortens url for domain and returns the short URL and its code
+func shortenOn(t *testing.T, h *handler.Handler, url, domain string) (string, string) {
+	t.Helper()
+	w := postShorten(h, handler.ShortenRequest{URL: url, Domain: domain})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for domain %q, got %d: %s", domain, w.Code, w.Body.String())
+	}
+	var resp handler.ShortenResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return resp.ShortURL, path.Base(resp.ShortURL)
+}
+
+func TestShortenURL_Domain(t *testing.T) {
+	_, h, _ := setupDomainsRouter(t, storage.NewMemoryStorage(nil))
+
+	if shortURL, code := shortenOn(t, h, "https://www.example.com/pinned", "GO.sho.rt"); shortURL != "https://go.sho.rt/"+code {
+		t.Fatalf("Expected a go.sho.rt short URL, got %s", shortURL)
+	}
+	if shortURL, code := shortenOn(t, h, "https://www.example.com/base", ""); shortURL != "https://sho.rt/"+code {
+		t.Fatalf("Expected the base URL, got %s", shortURL)
+	}
+	if shortURL, code := shortenOn(t, h, "https://www.example.com/base-host", "sho.rt"); shortURL != "https://sho.rt/"+code {
+		t.Fatalf("Expected the base URL for the base host, got %s", shortURL)
+	}
+}
+
+func TestShortenURL_DomainNotAllowed(t *testing.T) {
+	_, h, _ := setupDomainsRouter(t, storage.NewMemoryStorage(nil))
+
+	for _, domain := range []string{"evil.example", "sho.rt.evil.example", "go.sho.rt/path"} {
+		w := postShorten(h, handler.ShortenRequest{URL: "https://www.example.com/x", Domain: domain})
+		if w.Code != http.StatusBadRequest || errorCode(t, w) != handler.CodeInvalidDomain {
+			t.Errorf("%s: expected 400 invalid_domain, got %d", domain, w.Code)
+		}
+	}
+}
+
+func TestRedirect_PinnedDomain(t *testing.T) {
+	router, h, _ := setupDomainsRouter(t, storage.NewMemoryStorage(nil))
+	_, pinned := shortenOn(t, h, "https://www.example.com/pinned", "links.example")
+	_, everywhere := shortenOn(t, h, "https://www.example.com/everywhere", "")
+
+	for host, want := range map[string]int{
+		"links.example":     http.StatusFound,
+		"LINKS.example:443": http.StatusFound,
+		"sho.rt":            http.StatusNotFound,
+		"go.sho.rt":         http.StatusNotFound,
+	} {
+		if w := redirectOnHost(router, host, pinned); w.Code != want {
+			t.Errorf("pinned on %s: expected %d, got %d", host, want, w.Code)
+		}
+	}
+	for _, host := range []string{"sho.rt", "go.sho.rt", "links.example"} {
+		if w := redirectOnHost(router, host, everywhere); w.Code != http.StatusFound {
+			t.Errorf("unpinned on %s: expected 302, got %d", host, w.Code)
+		}
+	}
+}
+
+func TestShorten_DomainUnion(t *testing.T) {
+	router, h, svc := setupDomainsRouter(t, storage.NewMemoryStorage(nil))
+	ctx := context.Background()
+
+	firstURL, code := shortenOn(t, h, "https://www.example.com/both", "go.sho.rt")
+	if secondURL, _ := shortenOn(t, h, "https://www.example.com/both", "links.example"); secondURL != "https://links.example/"+code {
+		t.Fatalf("Expected one code on both domains, got %s and %s", firstURL, secondURL)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if _, _, err := svc.Shorten(ctx, "https://www.example.com/both", service.ShortenOptions{ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("Shorten failed: %v", err)
+	}
+	link, _ := svc.GetLink(ctx, code)
+	if !slices.Equal(link.Domains, []string{"go.sho.rt", "links.example", "sho.rt"}) || !link.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("Expected all three domains and the new expiry, got %+v", link)
+	}
+	for _, host := range []string{"sho.rt", "go.sho.rt", "links.example"} {
+		if w := redirectOnHost(router, host, code); w.Code != http.StatusFound {
+			t.Errorf("%s: expected 302, got %d", host, w.Code)
+		}
+	}
+
+	// an alias gains domains the same way, and a link served everywhere stays so
+	svc.Shorten(ctx, "https://www.example.com/alias", service.ShortenOptions{Alias: "promo", Domain: "go.sho.rt"})
+	svc.Shorten(ctx, "https://www.example.com/alias", service.ShortenOptions{Alias: "promo", Domain: "links.example"})
+	if link, _ := svc.GetLink(ctx, "promo"); !slices.Equal(link.Domains, []string{"go.sho.rt", "links.example"}) {
+		t.Fatalf("Expected the alias on both domains, got %v", link.Domains)
+	}
+	_, everywhere := shortenOn(t, h, "https://www.example.com/everywhere", "")
+	shortenOn(t, h, "https://www.example.com/everywhere", "go.sho.rt")
+	if link, _ := svc.GetLink(ctx, everywhere); link.Domains != nil {
+		t.Fatalf("Expected the link to stay on every domain, got %v", link.Domains)
+	}
+}
+
+func TestRedirect_PinnedDomainCached(t *testing.T) {
+	cache, backend := newCountedCache(storage.DefaultCacheOptions())
+	router, h, _ := setupDomainsRouter(t, cache)
+	_, pinned := shortenOn(t, h, "https://www.example.com/cached", "go.sho.rt")
+
+	for i := 0; i < 2; i++ {
+		if w := redirectOnHost(router, "go.sho.rt", pinned); w.Code != http.StatusFound {
+			t.Fatalf("Expected 302 on go.sho.rt, got %d", w.Code)
+		}
+		if w := redirectOnHost(router, "sho.rt", pinned); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected cached link to 404 on sho.rt, got %d", w.Code)
+		}
+	}
+	if backend.reads != 1 {
+		t.Fatalf("Expected only the first redirect to read the wrapped storage, got %d", backend.reads)
+	}
+}
+
+func TestGetLink_Domains(t *testing.T) {
+	router, h, _ := setupDomainsRouter(t, storage.NewMemoryStorage(nil))
+	_, pinned := shortenOn(t, h, "https://www.example.com/info", "links.example")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/"+pinned, nil))
+	var resp handler.LinkResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.ShortURL != "https://links.example/"+pinned || !slices.Equal(resp.Domains, []string{"links.example"}) {
+		t.Fatalf("Expected the link on links.example, got %+v", resp)
+	}
+}
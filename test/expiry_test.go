@@ -0,0 +1,227 @@
+/*
+This file contains unit tests for link expiration.
+
+- TestShortenURL_TTL: ttl_seconds sets an expires_at in the response roughly ttl seconds from now.
+- TestShortenURL_ExpiresAt: an RFC 3339 expires_at is echoed back in the response.
+- TestShortenURL_StoredExpiry: re-shortening a URL or alias without an expiry still reports the stored expires_at.
+- TestShortenURL_InvalidExpiry: negative TTLs, past timestamps, malformed timestamps and setting both fields yield 400 with the invalid_expiry code.
+- TestRedirectURL_Expired: an expired code answers 410 Gone.
+- TestMemoryStorage_PurgeExpired: the janitor sweep removes expired links from both maps and leaves live links alone.
+- TestMemoryStorage_ExpiredCodeReusable: an expired code can be claimed by a different URL and re-shortening the same URL creates a fresh link.
+- TestFileStorage_ExpiryPersists: the expiry of a link survives a reopen of the file backend.
+- TestService_Alias_KeepsExpiry: repeating an alias without an expiry keeps the alias's expiry, and a new expiry moves it, as on the hash path.
+*/
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func TestShortenURL_TTL(t *testing.T) {
+	h := setupHandler()
+
+	w := postShorten(h, handler.ShortenRequest{URL: "https://www.example.com/ttl", TTLSeconds: 3600})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp handler.ShortenResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	expiresAt, err := time.Parse(time.RFC3339, resp.ExpiresAt)
+	if err != nil {
+		t.Fatalf("Expected RFC 3339 expires_at, got %q", resp.ExpiresAt)
+	}
+	if d := time.Until(expiresAt); d < 59*time.Minute || d > 61*time.Minute {
+		t.Fatalf("Expected expiry about an hour from now, got %s", d)
+	}
+}
+
+func TestShortenURL_ExpiresAt(t *testing.T) {
+	h := setupHandler()
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+
+	w := postShorten(h, handler.ShortenRequest{URL: "https://www.example.com/abs", ExpiresAt: expiresAt})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp handler.ShortenResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.ExpiresAt != expiresAt {
+		t.Fatalf("Expected expires_at %s, got %s", expiresAt, resp.ExpiresAt)
+	}
+}
+
+func TestShortenURL_StoredExpiry(t *testing.T) {
+	h := setupHandler()
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+
+	for _, req := range []handler.ShortenRequest{
+		{URL: "https://www.example.com/stored"},
+		{URL: "https://www.example.com/stored-alias", Alias: "stored-alias"},
+	} {
+		withExpiry := req
+		withExpiry.ExpiresAt = expiresAt
+		if w := postShorten(h, withExpiry); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		w := postShorten(h, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 on repeat, got %d", w.Code)
+		}
+		var resp handler.ShortenResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp.ExpiresAt != expiresAt {
+			t.Fatalf("Expected stored expires_at %s for %+v, got %q", expiresAt, req, resp.ExpiresAt)
+		}
+	}
+}
+
+func TestShortenURL_InvalidExpiry(t *testing.T) {
+	testCases := []struct {
+		name string
+		req  handler.ShortenRequest
+	}{
+		{"Negative TTL", handler.ShortenRequest{URL: "https://example.com", TTLSeconds: -5}},
+		{"Past timestamp", handler.ShortenRequest{URL: "https://example.com", ExpiresAt: "2000-01-01T00:00:00Z"}},
+		{"Malformed timestamp", handler.ShortenRequest{URL: "https://example.com", ExpiresAt: "tomorrow"}},
+		{"Both set", handler.ShortenRequest{URL: "https://example.com", TTLSeconds: 60, ExpiresAt: "2999-01-01T00:00:00Z"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := postShorten(setupHandler(), tc.req)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d", w.Code)
+			}
+			var errResp handler.ErrorResponse
+			json.NewDecoder(w.Body).Decode(&errResp)
+			if errResp.Code != handler.CodeInvalidExpiry {
+				t.Fatalf("Expected invalid_expiry code, got %q", errResp.Code)
+			}
+		})
+	}
+}
+
+func TestRedirectURL_Expired(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	h := handler.NewHandler(service.NewURLService(store, "http://localhost:8080", nil, nil), nil, nil)
+
+	store.Save(context.Background(), storage.Link{
+		ShortCode: "expired1",
+		LongURL:   "https://www.example.com/old",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+
+	req := httptest.NewRequest("GET", "/expired1", nil)
+	w := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/{shortCode}", h.RedirectURL)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusGone {
+		t.Fatalf("Expected status 410, got %d", w.Code)
+	}
+}
+
+func TestMemoryStorage_PurgeExpired(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	store.Save(context.Background(), storage.Link{ShortCode: "expired1", LongURL: "https://www.example.com/old", ExpiresAt: time.Now().Add(-time.Minute)})
+	store.Save(context.Background(), storage.Link{ShortCode: "live0001", LongURL: "https://www.example.com/new", ExpiresAt: time.Now().Add(time.Hour)})
+
+	if n := store.PurgeExpired(); n != 1 {
+		t.Fatalf("Expected 1 purged link, got %d", n)
+	}
+
+	if _, err := store.GetLongURL(context.Background(), "expired1"); err != storage.ErrNotFound {
+		t.Fatalf("Expected purged code to be gone, got %v", err)
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/old"); err != storage.ErrNotFound {
+		t.Fatalf("Expected purged reverse mapping to be gone, got %v", err)
+	}
+	if _, err := store.GetLongURL(context.Background(), "live0001"); err != nil {
+		t.Fatalf("Expected live link to survive, got %v", err)
+	}
+}
+
+func TestMemoryStorage_ExpiredCodeReusable(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
+	longURL := "https://www.example.com/again"
+
+	store.Save(context.Background(), storage.Link{ShortCode: svc.GenerateShortCode(longURL), LongURL: longURL, ExpiresAt: time.Now().Add(-time.Minute)})
+
+	if store.Exists(context.Background(), svc.GenerateShortCode(longURL)) {
+		t.Fatal("Expected expired code to not exist")
+	}
+
+	_, code, err := svc.ShortenURL(context.Background(), longURL)
+	if err != nil {
+		t.Fatalf("Shorten failed: %v", err)
+	}
+	if _, err := svc.GetLongURL(context.Background(), code); err != nil {
+		t.Fatalf("Expected fresh link to resolve, got %v", err)
+	}
+
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "expired2", LongURL: "https://www.example.com/a", ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "expired2", LongURL: "https://www.example.com/b"}); err != nil {
+		t.Fatalf("Expected expired code to be claimable, got %v", err)
+	}
+}
+
+func TestFileStorage_ExpiryPersists(t *testing.T) {
+	dir := t.TempDir()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	store := openFileStorage(t, dir, storage.DefaultFileOptions())
+	store.Save(context.Background(), storage.Link{ShortCode: "ttl00001", LongURL: "https://www.example.com/ttl", ExpiresAt: expiresAt})
+	store.Close()
+
+	store = openFileStorage(t, dir, storage.DefaultFileOptions())
+	defer store.Close()
+
+	link, err := store.GetLink(context.Background(), "ttl00001")
+	if err != nil {
+		t.Fatalf("Expected link after reopen, got %v", err)
+	}
+	if !link.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("Expected expiry %s, got %s", expiresAt, link.ExpiresAt)
+	}
+	if link.CreatedAt.IsZero() {
+		t.Fatal("Expected creation time to persist")
+	}
+}
+
+func TestService_Alias_KeepsExpiry(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil)
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	svc.Shorten(ctx, "https://www.example.com/campaign", service.ShortenOptions{Alias: "campaign", ExpiresAt: expiresAt})
+	if _, _, err := svc.Shorten(ctx, "https://www.example.com/campaign", service.ShortenOptions{Alias: "campaign"}); err != nil {
+		t.Fatalf("Repeated alias failed: %v", err)
+	}
+	if link, _ := svc.GetLink(ctx, "campaign"); !link.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("Expected the alias to keep its expiry %v, got %v", expiresAt, link.ExpiresAt)
+	}
+
+	later := expiresAt.Add(time.Hour)
+	svc.Shorten(ctx, "https://www.example.com/campaign", service.ShortenOptions{Alias: "campaign", ExpiresAt: later})
+	if link, _ := svc.GetLink(ctx, "campaign"); !link.ExpiresAt.Equal(later) {
+		t.Fatalf("Expected a new expiry to move the alias's expiry to %v, got %v", later, link.ExpiresAt)
+	}
+}
@@ -0,0 +1,214 @@
+/*
+This file contains unit tests for exporting and importing the link database.
+
+- TestExportImport_RoundTrip: an export from one backend imports into another with codes, creation and expiry times, and reverse lookups intact.
+- TestImport_ConflictPolicies: skip keeps stored links, overwrite replaces them, fail writes nothing, and each reports its conflicts.
+- TestImport_DryRun: a dry run reports what would change without writing.
+- TestImport_InvalidRecord: a malformed or duplicate line, a reserved or non-URL-safe code, a non-http(s) long_url or a bad redirect_type fails the import with its line number before anything is written.
+- TestAdminExportImport: the admin endpoints stream an export and answer an import with its report, 409 on conflicts under policy=fail and 400 for a bad policy.
+- TestAdminImport_TooLarge: an import body over SetMaxImportBytes answers 413 with the import_too_large code and writes nothing.
+*/
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := storage.NewMemoryStorage(nil)
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	source.Save(ctx, storage.Link{ShortCode: "hash0001", LongURL: "https://www.example.com/a"})
+	source.SaveAlias(ctx, storage.Link{ShortCode: "my-alias", LongURL: "https://www.example.com/a"})
+	source.Save(ctx, storage.Link{ShortCode: "hash0002", LongURL: "https://www.example.com/b", ExpiresAt: expiresAt})
+
+	var buf bytes.Buffer
+	written, err := storage.Export(ctx, source, &buf)
+	if err != nil || written != 3 {
+		t.Fatalf("Expected 3 links exported, got %d, %v", written, err)
+	}
+
+	target := openSQLiteStorage(t, filepath.Join(t.TempDir(), "links.db"))
+	defer target.Close()
+	report, err := storage.Import(ctx, target, &buf, storage.ImportOptions{})
+	if err != nil || report.Records != 3 || report.Created != 3 {
+		t.Fatalf("Expected 3 links created, got %+v, %v", report, err)
+	}
+
+	for _, code := range []string{"hash0001", "my-alias", "hash0002"} {
+		want, _ := source.GetLink(ctx, code)
+		got, err := target.GetLink(ctx, code)
+		if err != nil || got.LongURL != want.LongURL || !got.CreatedAt.Equal(want.CreatedAt) || !got.ExpiresAt.Equal(want.ExpiresAt) {
+			t.Fatalf("Expected %+v after import, got %+v, %v", want, got, err)
+		}
+	}
+	if code, _ := target.GetShortCode(ctx, "https://www.example.com/a"); code != "hash0001" {
+		t.Fatalf("Expected alias to leave the reverse lookup alone, got %s", code)
+	}
+
+	// importing the same export again changes nothing
+	buf.Reset()
+	storage.Export(ctx, source, &buf)
+	report, _ = storage.Import(ctx, target, &buf, storage.ImportOptions{})
+	if report.Unchanged != 3 || report.Created != 0 {
+		t.Fatalf("Expected a repeated import to be unchanged, got %+v", report)
+	}
+}
+
+func TestImport_ConflictPolicies(t *testing.T) {
+	ctx := context.Background()
+	input := `{"code":"taken001","long_url":"https://www.example.com/incoming"}
+{"code":"fresh001","long_url":"https://www.example.com/fresh"}
+`
+	newStore := func() *storage.MemoryStorage {
+		store := storage.NewMemoryStorage(nil)
+		store.Save(ctx, storage.Link{ShortCode: "taken001", LongURL: "https://www.example.com/stored"})
+		return store
+	}
+
+	store := newStore()
+	report, err := storage.Import(ctx, store, strings.NewReader(input), storage.ImportOptions{Policy: storage.ConflictSkip})
+	if err != nil || report.Skipped != 1 || report.Created != 1 || len(report.Conflicts) != 1 || report.Conflicts[0].Line != 1 {
+		t.Fatalf("skip: unexpected report %+v, %v", report, err)
+	}
+	if longURL, _ := store.GetLongURL(ctx, "taken001"); longURL != "https://www.example.com/stored" {
+		t.Fatalf("skip: expected stored link kept, got %s", longURL)
+	}
+
+	store = newStore()
+	report, err = storage.Import(ctx, store, strings.NewReader(input), storage.ImportOptions{Policy: storage.ConflictOverwrite})
+	if err != nil || report.Overwritten != 1 || report.Created != 1 {
+		t.Fatalf("overwrite: unexpected report %+v, %v", report, err)
+	}
+	if longURL, _ := store.GetLongURL(ctx, "taken001"); longURL != "https://www.example.com/incoming" {
+		t.Fatalf("overwrite: expected imported link, got %s", longURL)
+	}
+	if _, err := store.GetShortCode(ctx, "https://www.example.com/stored"); err != storage.ErrNotFound {
+		t.Fatalf("overwrite: expected replaced URL to lose its reverse lookup, got %v", err)
+	}
+
+	store = newStore()
+	report, err = storage.Import(ctx, store, strings.NewReader(input), storage.ImportOptions{Policy: storage.ConflictFail})
+	if err != storage.ErrImportConflict || report.Conflicted != 1 {
+		t.Fatalf("fail: expected ErrImportConflict, got %+v, %v", report, err)
+	}
+	if store.Exists(ctx, "fresh001") {
+		t.Fatalf("fail: expected nothing written")
+	}
+}
+
+func TestImport_DryRun(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(nil)
+	store.Save(ctx, storage.Link{ShortCode: "taken001", LongURL: "https://www.example.com/stored"})
+
+	input := `{"code":"taken001","long_url":"https://www.example.com/incoming"}
+{"code":"fresh001","long_url":"https://www.example.com/fresh"}
+`
+	report, err := storage.Import(ctx, store, strings.NewReader(input), storage.ImportOptions{Policy: storage.ConflictOverwrite, DryRun: true})
+	if err != nil || !report.DryRun || report.Created != 1 || report.Overwritten != 1 {
+		t.Fatalf("Unexpected dry run report %+v, %v", report, err)
+	}
+	if store.Exists(ctx, "fresh001") {
+		t.Fatalf("Expected dry run not to create links")
+	}
+	if longURL, _ := store.GetLongURL(ctx, "taken001"); longURL != "https://www.example.com/stored" {
+		t.Fatalf("Expected dry run not to overwrite links, got %s", longURL)
+	}
+}
+
+func TestImport_InvalidRecord(t *testing.T) {
+	ctx := context.Background()
+	for name, input := range map[string]string{
+		"malformed":  "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{not json}\n",
+		"missing":    "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"nourl001\"}\n",
+		"duplicate":  "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/again\"}\n",
+		"redirect":   "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"bad00001\",\"long_url\":\"https://www.example.com/x\",\"redirect_type\":303}\n",
+		"javascript": "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"bad00001\",\"long_url\":\"javascript:alert(1)\"}\n",
+		"data":       "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"bad00001\",\"long_url\":\"data:text/html,hi\"}\n",
+		"reserved":   "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"Metrics\",\"long_url\":\"https://www.example.com/x\"}\n",
+		"api":        "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"api\",\"long_url\":\"https://www.example.com/x\"}\n",
+		"slash":      "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"a/b:c\",\"long_url\":\"https://www.example.com/x\"}\n",
+		"long":       "{\"code\":\"ok000001\",\"long_url\":\"https://www.example.com/ok\"}\n\n{\"code\":\"" + strings.Repeat("a", storage.MaxCodeLength+1) + "\",\"long_url\":\"https://www.example.com/x\"}\n",
+	} {
+		store := storage.NewMemoryStorage(nil)
+		_, err := storage.Import(ctx, store, strings.NewReader(input), storage.ImportOptions{})
+		if !errors.Is(err, storage.ErrInvalidRecord) || !strings.Contains(err.Error(), "line 3") {
+			t.Fatalf("%s: expected ErrInvalidRecord at line 3, got %v", name, err)
+		}
+		if store.Exists(ctx, "ok000001") {
+			t.Fatalf("%s: expected nothing written", name)
+		}
+	}
+}
+
+func TestAdminExportImport(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(nil)
+	h := handler.NewHandler(service.NewURLService(store, "http://localhost:8080", nil, nil), nil, nil)
+	store.Save(ctx, storage.Link{ShortCode: "hash0001", LongURL: "https://www.example.com/a"})
+
+	w := httptest.NewRecorder()
+	h.ExportLinks(w, httptest.NewRequest("GET", "/api/admin/export", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected NDJSON export, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var rec storage.ExportRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &rec); err != nil || rec.Code != "hash0001" {
+		t.Fatalf("Expected exported record, got %s, %v", w.Body.String(), err)
+	}
+
+	body := `{"code":"hash0001","long_url":"https://www.example.com/other"}` + "\n"
+	w = httptest.NewRecorder()
+	h.ImportLinks(w, httptest.NewRequest("POST", "/api/admin/import?policy=fail", strings.NewReader(body)))
+	var resp handler.ImportResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusConflict || resp.Code != handler.CodeImportConflict || len(resp.Conflicts) != 1 {
+		t.Fatalf("Expected 409 with the conflict, got %d %+v", w.Code, resp)
+	}
+
+	w = httptest.NewRecorder()
+	h.ImportLinks(w, httptest.NewRequest("POST", "/api/admin/import?policy=overwrite&dry_run=true", strings.NewReader(body)))
+	resp = handler.ImportResponse{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || !resp.DryRun || resp.Overwritten != 1 {
+		t.Fatalf("Expected dry run report, got %d %+v", w.Code, resp)
+	}
+
+	w = httptest.NewRecorder()
+	h.ImportLinks(w, httptest.NewRequest("POST", "/api/admin/import?policy=merge", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an unknown policy, got %d", w.Code)
+	}
+}
+
+func TestAdminImport_TooLarge(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage(nil)
+	h := handler.NewHandler(service.NewURLService(store, "http://localhost:8080", nil, nil), nil, nil)
+	h.SetMaxImportBytes(100)
+
+	body := `{"code":"first001","long_url":"https://www.example.com/first"}` + "\n" +
+		`{"code":"second01","long_url":"https://www.example.com/second"}` + "\n"
+	w := httptest.NewRecorder()
+	h.ImportLinks(w, httptest.NewRequest("POST", "/api/admin/import", strings.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge || errorCode(t, w) != handler.CodeImportTooLarge {
+		t.Fatalf("Expected 413 import_too_large, got %d %s", w.Code, w.Body.String())
+	}
+	if store.Exists(ctx, "first001") {
+		t.Fatal("Expected nothing written from an oversized import")
+	}
+}
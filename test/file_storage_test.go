@@ -0,0 +1,302 @@
+/*
+This file contains unit tests for the file-backed storage implementation.
+
+- TestFileStorage_PersistsAcrossReopen: mappings saved before Close are available after reopening the same directory.
+- TestFileStorage_TornRecord: a partially written final record (simulated crash) is discarded and earlier records survive.
+- TestFileStorage_Snapshot: the log is compacted into a snapshot and the mappings survive a reopen.
+- TestFileStorage_SyncPolicies: every fsync policy persists mappings once the storage is closed.
+- TestFileStorage_FailedAppend: a write the log could not take fails and leaves memory untouched, so nothing unlogged is served.
+- TestFileStorage_Locked: a directory held by an open storage cannot be opened again until it is closed.
+- TestFileStorage_PartialAppend: a short write or failed fsync is cut off the log, so later writes survive a reopen and the failed one does not come back; when the cut fails, further writes are refused.
+*/
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+func openFileStorage(t *testing.T, dir string, opts storage.FileOptions) *storage.FileStorage {
+	t.Helper()
+	store, err := storage.NewFileStorage(dir, opts)
+	if err != nil {
+		t.Fatalf("Failed to open file storage: %v", err)
+	}
+	return store
+}
+
+func TestFileStorage_PersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	store := openFileStorage(t, dir, storage.DefaultFileOptions())
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/persist"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store = openFileStorage(t, dir, storage.DefaultFileOptions())
+	defer store.Close()
+
+	longURL, err := store.GetLongURL(context.Background(), "abc12345")
+	if err != nil {
+		t.Fatalf("Expected mapping after reopen, got %v", err)
+	}
+	if longURL != "https://www.example.com/persist" {
+		t.Fatalf("Expected persisted long URL, got %s", longURL)
+	}
+
+	shortCode, err := store.GetShortCode(context.Background(), "https://www.example.com/persist")
+	if err != nil || shortCode != "abc12345" {
+		t.Fatalf("Expected reverse mapping after reopen, got %s, %v", shortCode, err)
+	}
+}
+
+func TestFileStorage_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	store := openFileStorage(t, dir, storage.DefaultFileOptions())
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "first001", LongURL: "https://www.example.com/first"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "second02", LongURL: "https://www.example.com/second"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	store.Close()
+
+	// chop the last few bytes off the final record to simulate a crash mid-write
+	walPath := filepath.Join(dir, "wal.log")
+	info, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatalf("Failed to stat wal: %v", err)
+	}
+	if err := os.Truncate(walPath, info.Size()-5); err != nil {
+		t.Fatalf("Failed to truncate wal: %v", err)
+	}
+
+	store = openFileStorage(t, dir, storage.DefaultFileOptions())
+
+	if _, err := store.GetLongURL(context.Background(), "first001"); err != nil {
+		t.Fatalf("Expected intact record to survive, got %v", err)
+	}
+	if _, err := store.GetLongURL(context.Background(), "second02"); err != storage.ErrNotFound {
+		t.Fatalf("Expected torn record to be discarded, got %v", err)
+	}
+
+	// the log must remain appendable after recovery
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "third003", LongURL: "https://www.example.com/third"}); err != nil {
+		t.Fatalf("Save after recovery failed: %v", err)
+	}
+	store.Close()
+
+	store = openFileStorage(t, dir, storage.DefaultFileOptions())
+	defer store.Close()
+	if _, err := store.GetLongURL(context.Background(), "third003"); err != nil {
+		t.Fatalf("Expected record written after recovery, got %v", err)
+	}
+}
+
+func TestFileStorage_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+	opts := storage.DefaultFileOptions()
+	opts.SnapshotEvery = 2
+
+	store := openFileStorage(t, dir, opts)
+	urls := map[string]string{
+		"snap0001": "https://www.example.com/1",
+		"snap0002": "https://www.example.com/2",
+		"snap0003": "https://www.example.com/3",
+	}
+	for code, url := range urls {
+		if err := store.Save(context.Background(), storage.Link{ShortCode: code, LongURL: url}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	store.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, "snapshot.json")); err != nil {
+		t.Fatalf("Expected snapshot file, got %v", err)
+	}
+
+	store = openFileStorage(t, dir, opts)
+	defer store.Close()
+	for code, url := range urls {
+		got, err := store.GetLongURL(context.Background(), code)
+		if err != nil || got != url {
+			t.Fatalf("Expected %s -> %s after reopen, got %s, %v", code, url, got, err)
+		}
+	}
+}
+
+func TestFileStorage_SyncPolicies(t *testing.T) {
+	for _, policy := range []storage.SyncPolicy{storage.SyncAlways, storage.SyncInterval, storage.SyncNever} {
+		t.Run(string(policy), func(t *testing.T) {
+			dir := t.TempDir()
+			opts := storage.DefaultFileOptions()
+			opts.Sync = policy
+
+			store := openFileStorage(t, dir, opts)
+			if err := store.Save(context.Background(), storage.Link{ShortCode: "policy01", LongURL: "https://www.example.com/policy"}); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			store.Close()
+
+			store = openFileStorage(t, dir, opts)
+			defer store.Close()
+			if _, err := store.GetLongURL(context.Background(), "policy01"); err != nil {
+				t.Fatalf("Expected mapping with fsync=%s, got %v", policy, err)
+			}
+		})
+	}
+}
+
+// failingLog is a write-ahead log on a disk that fails once failing is set.
+// With short set, half of each write lands before the failure; with
+// failSync, writes land but fsync fails; failTruncate fails truncates.
+type failingLog struct {
+	storage.LogFile
+	failing      bool
+	short        bool
+	failSync     bool
+	failTruncate bool
+}
+
+var errDiskFull = errors.New("no space left on device")
+
+func (l *failingLog) Write(p []byte) (int, error) {
+	if l.short {
+		n, _ := l.LogFile.Write(p[:len(p)/2])
+		return n, errDiskFull
+	}
+	if l.failing {
+		return 0, errDiskFull
+	}
+	return l.LogFile.Write(p)
+}
+
+func (l *failingLog) Truncate(size int64) error {
+	if l.failTruncate {
+		return errDiskFull
+	}
+	return l.LogFile.Truncate(size)
+}
+
+func (l *failingLog) Sync() error {
+	if l.failSync {
+		return errDiskFull
+	}
+	return l.LogFile.Sync()
+}
+
+// openFailingLog opens the file storage in dir on top of log
+func openFailingLog(t *testing.T, dir string, log *failingLog) *storage.FileStorage {
+	t.Helper()
+	opts := storage.DefaultFileOptions()
+	opts.OpenLog = func(path string) (storage.LogFile, error) {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		log.LogFile = f
+		return log, err
+	}
+	return openFileStorage(t, dir, opts)
+}
+
+func TestFileStorage_FailedAppend(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	log := &failingLog{}
+	store := openFailingLog(t, dir, log)
+	store.Save(ctx, storage.Link{ShortCode: "kept0001", LongURL: "https://www.example.com/kept"})
+	log.failing = true
+
+	if err := store.Save(ctx, storage.Link{ShortCode: "lost0001", LongURL: "https://www.example.com/lost"}); !errors.Is(err, errDiskFull) {
+		t.Fatalf("Expected Save to fail with the disk error, got %v", err)
+	}
+	if store.Exists(ctx, "lost0001") {
+		t.Fatal("Expected a link the log did not take to stay out of memory")
+	}
+	if _, err := store.GetShortCode(ctx, "https://www.example.com/lost"); err != storage.ErrNotFound {
+		t.Fatalf("Expected no reverse lookup for the lost link, got %v", err)
+	}
+	if err := store.Update(ctx, "kept0001", "https://www.example.com/moved"); !errors.Is(err, errDiskFull) {
+		t.Fatalf("Expected Update to fail with the disk error, got %v", err)
+	}
+	if err := store.Delete(ctx, "kept0001"); !errors.Is(err, errDiskFull) {
+		t.Fatalf("Expected Delete to fail with the disk error, got %v", err)
+	}
+	if longURL, _ := store.GetLongURL(ctx, "kept0001"); longURL != "https://www.example.com/kept" {
+		t.Fatalf("Expected failed writes to leave kept0001 alone, got %s", longURL)
+	}
+
+	log.failing = false
+	store.Close()
+	store = openFileStorage(t, dir, storage.DefaultFileOptions())
+	defer store.Close()
+	if longURL, _ := store.GetLongURL(ctx, "kept0001"); longURL != "https://www.example.com/kept" || store.Exists(ctx, "lost0001") {
+		t.Fatalf("Expected the reopened storage to match what was served, got kept0001 -> %s", longURL)
+	}
+}
+
+func TestFileStorage_PartialAppend(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	log := &failingLog{}
+	store := openFailingLog(t, dir, log)
+	store.Save(ctx, storage.Link{ShortCode: "before01", LongURL: "https://www.example.com/before"})
+
+	log.short = true
+	if err := store.Save(ctx, storage.Link{ShortCode: "torn0001", LongURL: "https://www.example.com/torn"}); !errors.Is(err, errDiskFull) {
+		t.Fatalf("Expected the short write to fail, got %v", err)
+	}
+	log.short, log.failSync = false, true
+	if err := store.Save(ctx, storage.Link{ShortCode: "unsync01", LongURL: "https://www.example.com/unsynced"}); !errors.Is(err, errDiskFull) {
+		t.Fatalf("Expected the failed fsync to fail the save, got %v", err)
+	}
+	log.failSync = false
+	if err := store.Save(ctx, storage.Link{ShortCode: "after001", LongURL: "https://www.example.com/after"}); err != nil {
+		t.Fatalf("Save after the failures failed: %v", err)
+	}
+
+	// a torn frame that cannot be cut off stops all further writes
+	log.short, log.failTruncate = true, true
+	store.Save(ctx, storage.Link{ShortCode: "stuck001", LongURL: "https://www.example.com/stuck"})
+	log.short, log.failTruncate = false, false
+	if err := store.Save(ctx, storage.Link{ShortCode: "refused1", LongURL: "https://www.example.com/refused"}); err == nil {
+		t.Fatal("Expected writes to be refused behind an untruncated torn frame")
+	}
+	if err := storage.Ping(ctx, store); err == nil {
+		t.Fatal("Expected Ping to report the failed log")
+	}
+	store.Close()
+
+	store = openFileStorage(t, dir, storage.DefaultFileOptions())
+	defer store.Close()
+	for _, code := range []string{"before01", "after001"} {
+		if !store.Exists(ctx, code) {
+			t.Fatalf("Expected %s to survive the reopen", code)
+		}
+	}
+	for _, code := range []string{"torn0001", "unsync01", "stuck001", "refused1"} {
+		if store.Exists(ctx, code) {
+			t.Fatalf("Expected the failed write %s to stay lost", code)
+		}
+	}
+}
+
+func TestFileStorage_Locked(t *testing.T) {
+	dir := t.TempDir()
+	store := openFileStorage(t, dir, storage.DefaultFileOptions())
+
+	if _, err := storage.NewFileStorage(dir, storage.DefaultFileOptions()); !errors.Is(err, storage.ErrLocked) {
+		t.Fatalf("Expected ErrLocked while the directory is held, got %v", err)
+	}
+	store.Close()
+
+	store = openFileStorage(t, dir, storage.DefaultFileOptions())
+	store.Close()
+}
@@ -0,0 +1,147 @@
+/*
+This file contains unit tests for the short code generation strategies.
+
+- TestNewCodeGenerator_Strategies: every named strategy yields codes of the configured length drawn from its alphabet.
+- TestNewCodeGenerator_Invalid: unknown strategies and out of range lengths are rejected.
+- TestHashGenerator_MatchesDefault: the default hash generator keeps the existing 8 character codes.
+- TestCounterGenerator_Sequence: the counter hands out consecutive padded base62 codes.
+- TestSeedCounter_SkipsExisting: seeding from storage moves the counter past codes it issued before a restart.
+- TestService_CounterStrategy: a service using the counter stays idempotent per URL and gives new URLs new codes.
+*/
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+func TestNewCodeGenerator_Strategies(t *testing.T) {
+	testCases := []struct {
+		strategy string
+		length   int
+		alphabet string
+	}{
+		{service.StrategyHash, 10, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"},
+		{service.StrategyCounter, 6, service.Base62Alphabet},
+		{service.StrategyRandom, 12, service.Base62Alphabet},
+		{service.StrategyReadable, 7, service.ReadableAlphabet},
+	}
+
+	for _, tc := range testCases {
+		gen, err := service.NewCodeGenerator(tc.strategy, tc.length)
+		if err != nil {
+			t.Fatalf("NewCodeGenerator(%q) failed: %v", tc.strategy, err)
+		}
+		for i := 0; i < 50; i++ {
+			code, err := gen.Generate("https://www.example.com", i)
+			if err != nil {
+				t.Fatalf("%s: Generate failed: %v", tc.strategy, err)
+			}
+			if len(code) != tc.length {
+				t.Fatalf("%s: expected length %d, got %q", tc.strategy, tc.length, code)
+			}
+			for _, c := range code {
+				if !strings.ContainsRune(tc.alphabet, c) {
+					t.Fatalf("%s: unexpected character %q in %q", tc.strategy, c, code)
+				}
+			}
+		}
+	}
+
+	for _, c := range "0O1lI" {
+		if strings.ContainsRune(service.ReadableAlphabet, c) {
+			t.Fatalf("Readable alphabet contains look-alike %q", c)
+		}
+	}
+}
+
+func TestNewCodeGenerator_Invalid(t *testing.T) {
+	if _, err := service.NewCodeGenerator("sequential", 8); err == nil {
+		t.Fatalf("Expected unknown strategy to fail")
+	}
+	for _, length := range []int{0, service.MinCodeLength - 1, service.MaxCodeLength + 1} {
+		if _, err := service.NewCodeGenerator(service.StrategyRandom, length); err == nil {
+			t.Fatalf("Expected length %d to fail", length)
+		}
+	}
+}
+
+func TestHashGenerator_MatchesDefault(t *testing.T) {
+	gen := service.NewHashGenerator(service.DefaultCodeLength)
+	for attempt := 0; attempt < 3; attempt++ {
+		code, _ := gen.Generate("https://www.example.com", attempt)
+		if code != service.HashShortCode("https://www.example.com", attempt) {
+			t.Fatalf("Expected hash generator to match HashShortCode on attempt %d", attempt)
+		}
+	}
+
+	svc := service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil)
+	if svc.GenerateShortCode("https://www.example.com") != service.HashShortCode("https://www.example.com", 0) {
+		t.Fatalf("Expected nil generator to default to hash codes")
+	}
+}
+
+func TestCounterGenerator_Sequence(t *testing.T) {
+	gen := service.NewCounterGenerator(4)
+
+	want := []string{"0000", "0001", "0002"}
+	for _, w := range want {
+		if code, _ := gen.Generate("https://www.example.com", 0); code != w {
+			t.Fatalf("Expected %s, got %s", w, code)
+		}
+	}
+
+	gen.Observe("000z")
+	if code, _ := gen.Generate("https://www.example.com", 0); code != "0010" {
+		t.Fatalf("Expected counter to continue past observed code, got %s", code)
+	}
+}
+
+func TestSeedCounter_SkipsExisting(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", service.NewCounterGenerator(6), nil)
+	for _, u := range []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"} {
+		if _, _, err := svc.ShortenURL(context.Background(), u); err != nil {
+			t.Fatalf("Shorten failed: %v", err)
+		}
+	}
+	store.SaveAlias(context.Background(), storage.Link{ShortCode: "my-alias", LongURL: "https://d.example.com"})
+
+	// a fresh counter, as after a restart
+	gen := service.NewCounterGenerator(6)
+	if err := service.SeedCounter(context.Background(), gen, store); err != nil {
+		t.Fatalf("SeedCounter failed: %v", err)
+	}
+	if code, _ := gen.Generate("https://e.example.com", 0); code != "000003" {
+		t.Fatalf("Expected seeded counter to continue at 000003, got %s", code)
+	}
+}
+
+func TestService_CounterStrategy(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	// occupy the counter's first value so the service must skip it
+	store.SaveAlias(context.Background(), storage.Link{ShortCode: "00000000", LongURL: "https://taken.example.com"})
+	svc := service.NewURLService(store, "http://localhost:8080", service.NewCounterGenerator(service.DefaultCodeLength), nil)
+
+	_, first, err := svc.ShortenURL(context.Background(), "https://www.example.com/a")
+	if err != nil {
+		t.Fatalf("Shorten failed: %v", err)
+	}
+	if first != "00000001" {
+		t.Fatalf("Expected taken code to be skipped, got %s", first)
+	}
+
+	_, again, _ := svc.ShortenURL(context.Background(), "https://www.example.com/a")
+	if again != first {
+		t.Fatalf("Expected same URL to keep its code, got %s and %s", first, again)
+	}
+
+	_, second, _ := svc.ShortenURL(context.Background(), "https://www.example.com/b")
+	if second != "00000002" {
+		t.Fatalf("Expected next counter value, got %s", second)
+	}
+}
@@ -35,9 +35,9 @@ import (
 )
 
 func setupHandler() *handler.Handler {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
-	return handler.NewHandler(svc)
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
+	return handler.NewHandler(svc, nil, nil)
 }
 
 func TestShortenURL_Success(t *testing.T) {
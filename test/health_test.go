@@ -0,0 +1,100 @@
+/*
+This file contains unit tests for the liveness and readiness probes.
+
+- TestHealthz: GET /healthz answers 200 whatever the storage state.
+- TestReadyz_StorageDown: GET /readyz answers 200 while storage can serve and 503 once the file or sqlite backend is closed, through the cache too.
+- TestReadyz_Draining: after Drain, /readyz answers 503 while other routes keep serving in-flight traffic.
+*/
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func probe(h *handler.Handler, target string) (int, handler.HealthResponse) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", target, nil)
+	if target == "/healthz" {
+		h.Healthz(w, req)
+	} else {
+		h.Readyz(w, req)
+	}
+
+	var resp handler.HealthResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return w.Code, resp
+}
+
+func TestHealthz(t *testing.T) {
+	store, err := storage.NewFileStorage(t.TempDir(), storage.DefaultFileOptions())
+	if err != nil {
+		t.Fatalf("Failed to open file storage: %v", err)
+	}
+	h := handler.NewHandler(service.NewURLService(store, "http://localhost:8080", nil, nil), nil, nil)
+
+	store.Close()
+	if code, resp := probe(h, "/healthz"); code != http.StatusOK || resp.Status != "ok" {
+		t.Fatalf("Expected 200 ok with storage closed, got %d %+v", code, resp)
+	}
+}
+
+func TestReadyz_StorageDown(t *testing.T) {
+	fileStore, err := storage.NewFileStorage(t.TempDir(), storage.DefaultFileOptions())
+	if err != nil {
+		t.Fatalf("Failed to open file storage: %v", err)
+	}
+	sqliteStore := openSQLiteStorage(t, filepath.Join(t.TempDir(), "links.db"))
+
+	for name, backend := range map[string]interface {
+		storage.Storage
+		Close() error
+	}{
+		"file":   fileStore,
+		"sqlite": sqliteStore,
+	} {
+		cached := storage.NewCachedStorage(backend, storage.DefaultCacheOptions())
+		h := handler.NewHandler(service.NewURLService(cached, "http://localhost:8080", nil, nil), nil, nil)
+
+		if code, resp := probe(h, "/readyz"); code != http.StatusOK || resp.Status != "ready" {
+			t.Fatalf("%s: expected 200 ready, got %d %+v", name, code, resp)
+		}
+
+		backend.Close()
+		if code, resp := probe(h, "/readyz"); code != http.StatusServiceUnavailable || resp.Status != "unavailable" || resp.Error == "" {
+			t.Fatalf("%s: expected 503 once closed, got %d %+v", name, code, resp)
+		}
+	}
+}
+
+func TestReadyz_Draining(t *testing.T) {
+	svc := service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil)
+	h := handler.NewHandler(svc, nil, nil)
+	router := mux.NewRouter()
+	router.HandleFunc("/{shortCode}", h.RedirectURL).Methods("GET")
+	_, code, _ := svc.ShortenURL(context.Background(), "https://www.example.com/draining")
+
+	h.Drain()
+	if status, resp := probe(h, "/readyz"); status != http.StatusServiceUnavailable || resp.Status != "draining" {
+		t.Fatalf("Expected 503 draining, got %d %+v", status, resp)
+	}
+	if status, _ := probe(h, "/healthz"); status != http.StatusOK {
+		t.Fatalf("Expected liveness unaffected by draining, got %d", status)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected redirects to keep working while draining, got %d", w.Code)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+This file contains unit tests for the link management API.
+
+- TestGetLink_Success: GET /api/links/{shortCode} returns the link's short URL, long URL and creation time.
+- TestGetLink_NotFound: metadata for an unknown code yields 404.
+- TestUpdateLink_Success: PATCH retargets the destination while keeping the code, and the redirect follows the new URL.
+- TestUpdateLink_Invalid: PATCH with an invalid URL yields 400 and an unknown code yields 404.
+- TestUpdateLink_ReverseIndex: after retargeting, shortening the old URL no longer returns the retargeted code and shortening the new URL returns it.
+- TestDeleteLink_Success: DELETE returns 204, the code stops redirecting and shortening the URL again recreates it.
+- TestDeleteLink_NotFound: deleting an unknown code yields 404.
+- TestFileStorage_UpdateDeletePersist: updates and deletes survive a reopen of the file backend.
+*/
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func setupLinksRouter() (*mux.Router, *service.URLService) {
+	svc := service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil)
+	h := handler.NewHandler(svc, nil, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/links/{shortCode}", h.GetLink).Methods("GET")
+	router.HandleFunc("/api/links/{shortCode}", h.UpdateLink).Methods("PATCH")
+	router.HandleFunc("/api/links/{shortCode}", h.DeleteLink).Methods("DELETE")
+	router.HandleFunc("/{shortCode}", h.RedirectURL).Methods("GET", "HEAD")
+	return router, svc
+}
+
+func patchLink(router *mux.Router, shortCode, url string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(handler.UpdateLinkRequest{URL: url})
+	req := httptest.NewRequest("PATCH", "/api/links/"+shortCode, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetLink_Success(t *testing.T) {
+	router, svc := setupLinksRouter()
+	shortURL, code, _ := svc.ShortenURL(context.Background(), "https://www.example.com/info")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/"+code, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp handler.LinkResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.ShortCode != code || resp.ShortURL != shortURL || resp.LongURL != "https://www.example.com/info" {
+		t.Fatalf("Unexpected link metadata: %+v", resp)
+	}
+	if resp.CreatedAt == "" || resp.Expired {
+		t.Fatalf("Expected creation time and live link, got %+v", resp)
+	}
+}
+
+func TestGetLink_NotFound(t *testing.T) {
+	router, _ := setupLinksRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/nonexistent", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestUpdateLink_Success(t *testing.T) {
+	router, svc := setupLinksRouter()
+	_, code, _ := svc.ShortenURL(context.Background(), "https://www.example.com/old")
+
+	w := patchLink(router, code, "https://www.example.com/new")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp handler.LinkResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.ShortCode != code || resp.LongURL != "https://www.example.com/new" {
+		t.Fatalf("Unexpected link after update: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if location := w.Header().Get("Location"); location != "https://www.example.com/new" {
+		t.Fatalf("Expected redirect to new URL, got %s", location)
+	}
+}
+
+func TestUpdateLink_Invalid(t *testing.T) {
+	router, svc := setupLinksRouter()
+	_, code, _ := svc.ShortenURL(context.Background(), "https://www.example.com/old")
+
+	if w := patchLink(router, code, "not-a-url"); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	if w := patchLink(router, "nonexistent", "https://www.example.com/new"); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestUpdateLink_ReverseIndex(t *testing.T) {
+	router, svc := setupLinksRouter()
+	oldURL := "https://www.example.com/old"
+	newURL := "https://www.example.com/new"
+	_, code, _ := svc.ShortenURL(context.Background(), oldURL)
+
+	patchLink(router, code, newURL)
+
+	_, oldCode, err := svc.ShortenURL(context.Background(), oldURL)
+	if err != nil {
+		t.Fatalf("Shorten failed: %v", err)
+	}
+	if oldCode == code {
+		t.Fatalf("Expected old URL to get a new code, got the retargeted %s", code)
+	}
+	if longURL, _ := svc.GetLongURL(context.Background(), oldCode); longURL != oldURL {
+		t.Fatalf("Expected new code to point at old URL, got %s", longURL)
+	}
+
+	_, newCode, _ := svc.ShortenURL(context.Background(), newURL)
+	if newCode != code {
+		t.Fatalf("Expected new URL to reuse retargeted code %s, got %s", code, newCode)
+	}
+}
+
+func TestDeleteLink_Success(t *testing.T) {
+	router, svc := setupLinksRouter()
+	longURL := "https://www.example.com/gone"
+	_, code, _ := svc.ShortenURL(context.Background(), longURL)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/links/"+code, nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404 after delete, got %d", w.Code)
+	}
+
+	_, again, err := svc.ShortenURL(context.Background(), longURL)
+	if err != nil || again != code {
+		t.Fatalf("Expected re-shortening to recreate %s, got %s, %v", code, again, err)
+	}
+}
+
+func TestDeleteLink_NotFound(t *testing.T) {
+	router, _ := setupLinksRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/links/nonexistent", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestFileStorage_UpdateDeletePersist(t *testing.T) {
+	dir := t.TempDir()
+
+	store := openFileStorage(t, dir, storage.DefaultFileOptions())
+	store.Save(context.Background(), storage.Link{ShortCode: "update01", LongURL: "https://www.example.com/old"})
+	store.Save(context.Background(), storage.Link{ShortCode: "delete01", LongURL: "https://www.example.com/doomed"})
+	if err := store.Update(context.Background(), "update01", "https://www.example.com/new"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := store.Delete(context.Background(), "delete01"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	store.Close()
+
+	store = openFileStorage(t, dir, storage.DefaultFileOptions())
+	defer store.Close()
+
+	if longURL, _ := store.GetLongURL(context.Background(), "update01"); longURL != "https://www.example.com/new" {
+		t.Fatalf("Expected update to persist, got %s", longURL)
+	}
+	if code, _ := store.GetShortCode(context.Background(), "https://www.example.com/new"); code != "update01" {
+		t.Fatalf("Expected reverse lookup to follow the update, got %s", code)
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/old"); err != storage.ErrNotFound {
+		t.Fatalf("Expected old reverse lookup to be gone, got %v", err)
+	}
+	if _, err := store.GetLongURL(context.Background(), "delete01"); err != storage.ErrNotFound {
+		t.Fatalf("Expected delete to persist, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+This file contains unit tests for link listing and search.
+
+- TestMemoryStorage_List_Pagination: walking every page with the returned cursor yields each link exactly once in creation order, even when creation times tie.
+- TestMemoryStorage_List_StableAcrossInserts: links created after a page was served appear on later pages without shifting earlier ones.
+- TestMemoryStorage_List_Filters: q matches a substring of the code or long URL and host matches the long URL's host, both case-insensitively.
+- TestMemoryStorage_List_InvalidCursor: a malformed cursor yields ErrInvalidCursor.
+- TestListLinks_Handler: GET /api/links returns links and next_cursor, and rejects a bad limit or cursor with 400.
+*/
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+// collectPages walks every page of the listing and returns the codes in order
+func collectPages(t *testing.T, store storage.Storage, opts storage.ListOptions) []string {
+	t.Helper()
+	var codes []string
+	for i := 0; i < 100; i++ {
+		page, err := store.List(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		for _, link := range page.Links {
+			codes = append(codes, link.ShortCode)
+		}
+		if page.NextCursor == "" {
+			return codes
+		}
+		opts.Cursor = page.NextCursor
+	}
+	t.Fatal("Listing did not terminate")
+	return nil
+}
+
+func TestMemoryStorage_List_Pagination(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var want []string
+	for i := 0; i < 7; i++ {
+		code := fmt.Sprintf("code%04d", i)
+		// pairs of links share a creation time to exercise the tie-breaker
+		store.Save(context.Background(), storage.Link{ShortCode: code, LongURL: fmt.Sprintf("https://www.example.com/%d", i), CreatedAt: base.Add(time.Duration(i/2) * time.Second)})
+		want = append(want, code)
+	}
+
+	got := collectPages(t, store, storage.ListOptions{Limit: 3})
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestMemoryStorage_List_StableAcrossInserts(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		store.Save(context.Background(), storage.Link{ShortCode: fmt.Sprintf("code%04d", i), LongURL: fmt.Sprintf("https://www.example.com/%d", i), CreatedAt: base.Add(time.Duration(i) * time.Second)})
+	}
+
+	first, _ := store.List(context.Background(), storage.ListOptions{Limit: 2})
+	store.Save(context.Background(), storage.Link{ShortCode: "late0000", LongURL: "https://www.example.com/late", CreatedAt: base.Add(time.Hour)})
+
+	rest := collectPages(t, store, storage.ListOptions{Limit: 2, Cursor: first.NextCursor})
+	want := []string{"code0002", "code0003", "late0000"}
+	if fmt.Sprint(rest) != fmt.Sprint(want) {
+		t.Fatalf("Expected %v, got %v", want, rest)
+	}
+}
+
+func TestMemoryStorage_List_Filters(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+	store.Save(context.Background(), storage.Link{ShortCode: "aaaa0001", LongURL: "https://Docs.Example.com/Guide"})
+	store.Save(context.Background(), storage.Link{ShortCode: "bbbb0002", LongURL: "https://blog.example.com/guide-two"})
+	store.Save(context.Background(), storage.Link{ShortCode: "cccc0003", LongURL: "https://other.org/page"})
+
+	byQuery := collectPages(t, store, storage.ListOptions{Query: "GUIDE"})
+	if len(byQuery) != 2 {
+		t.Fatalf("Expected 2 links matching q, got %v", byQuery)
+	}
+
+	byCode := collectPages(t, store, storage.ListOptions{Query: "cccc"})
+	if len(byCode) != 1 || byCode[0] != "cccc0003" {
+		t.Fatalf("Expected code match, got %v", byCode)
+	}
+
+	byHost := collectPages(t, store, storage.ListOptions{Host: "docs.example.com"})
+	if len(byHost) != 1 || byHost[0] != "aaaa0001" {
+		t.Fatalf("Expected host match, got %v", byHost)
+	}
+
+	both := collectPages(t, store, storage.ListOptions{Query: "two", Host: "docs.example.com"})
+	if len(both) != 0 {
+		t.Fatalf("Expected no links matching both filters, got %v", both)
+	}
+}
+
+func TestMemoryStorage_List_InvalidCursor(t *testing.T) {
+	store := storage.NewMemoryStorage(nil)
+
+	if _, err := store.List(context.Background(), storage.ListOptions{Cursor: "!!not-a-cursor"}); err != storage.ErrInvalidCursor {
+		t.Fatalf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestListLinks_Handler(t *testing.T) {
+	svc := service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil)
+	h := handler.NewHandler(svc, nil, nil)
+	for i := 0; i < 3; i++ {
+		svc.ShortenURL(context.Background(), fmt.Sprintf("https://www.example.com/%d", i))
+	}
+
+	w := httptest.NewRecorder()
+	h.ListLinks(w, httptest.NewRequest("GET", "/api/links?limit=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp handler.ListLinksResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Links) != 2 || resp.NextCursor == "" {
+		t.Fatalf("Expected a full first page with a cursor, got %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	h.ListLinks(w, httptest.NewRequest("GET", "/api/links?limit=2&cursor="+resp.NextCursor, nil))
+	var last handler.ListLinksResponse
+	json.NewDecoder(w.Body).Decode(&last)
+	if len(last.Links) != 1 || last.NextCursor != "" {
+		t.Fatalf("Expected a final page of one link, got %+v", last)
+	}
+
+	for _, query := range []string{"limit=0", "limit=abc", "cursor=bogus"} {
+		w = httptest.NewRecorder()
+		h.ListLinks(w, httptest.NewRequest("GET", "/api/links?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+/*
+This file contains unit tests for the Prometheus metrics endpoint.
+
+- TestRegistry_Exposition: counters, histograms and gauge funcs are written in the text exposition format with cumulative buckets and escaped label values.
+- TestMetrics_ShortenOutcomes: shorten requests are counted as created, existing, invalid or conflict, batch items included.
+- TestMetrics_Redirects: redirects are counted by status, 302 for a known code and 404 for an unknown one.
+- TestMetrics_RouteLatency: every request is timed under its route template, and GET /metrics serves all series.
+- TestStorage_Len: the in-memory stores count held links across saves, aliases, retargets and deletes.
+*/
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/metrics"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func setupMetricsRouter() (*mux.Router, *metrics.Server) {
+	store := storage.NewMemoryStorage(nil)
+	h := handler.NewHandler(service.NewURLService(store, "http://localhost:8080", nil, nil), nil, nil)
+	m := metrics.NewServer()
+	h.SetMetrics(m)
+	m.NewGaugeFunc("urlshortener_storage_links", "Links held in memory.", func() float64 { return float64(store.Len()) })
+
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.HandleFunc("/api/shorten", h.ShortenURL).Methods("POST")
+	router.HandleFunc("/api/shorten/batch", h.ShortenBatch).Methods("POST")
+	router.Handle("/metrics", m).Methods("GET")
+	router.HandleFunc("/{shortCode}", h.RedirectURL).Methods("GET", "HEAD")
+	return router, m
+}
+
+func serve(router *mux.Router, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegistry_Exposition(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := r.NewCounter("test_requests_total", "Requests.", "path")
+	c.Inc("/a")
+	c.Add(2, "/a")
+	c.Inc(`say "hi"`)
+	h := r.NewHistogram("test_duration_seconds", "Duration.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(5)
+	r.NewGaugeFunc("test_size", "Size.", func() float64 { return 7 })
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	want := `# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{path="/a"} 3
+test_requests_total{path="say \"hi\""} 1
+# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 2
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 5.15
+test_duration_seconds_count 3
+# HELP test_size Size.
+# TYPE test_size gauge
+test_size 7
+`
+	if buf.String() != want {
+		t.Fatalf("Unexpected exposition:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestMetrics_ShortenOutcomes(t *testing.T) {
+	router, m := setupMetricsRouter()
+
+	serve(router, "POST", "/api/shorten", `{"url":"https://www.example.com/metrics"}`)
+	serve(router, "POST", "/api/shorten", `{"url":"https://www.example.com/metrics"}`)
+	serve(router, "POST", "/api/shorten", `{"url":"not a url"}`)
+	serve(router, "POST", "/api/shorten", `{not json`)
+	serve(router, "POST", "/api/shorten", `{"url":"https://www.example.com/one","alias":"taken-alias"}`)
+	serve(router, "POST", "/api/shorten", `{"url":"https://www.example.com/two","alias":"taken-alias"}`)
+	serve(router, "POST", "/api/shorten/batch", `[{"url":"https://www.example.com/metrics"},{"url":"https://www.example.com/batch"},{"url":""}]`)
+
+	for outcome, want := range map[string]uint64{
+		metrics.OutcomeCreated:  3,
+		metrics.OutcomeExisting: 2,
+		metrics.OutcomeInvalid:  3,
+		metrics.OutcomeConflict: 1,
+		metrics.OutcomeError:    0,
+	} {
+		if got := m.ShortenCount(outcome); got != want {
+			t.Errorf("Expected %d %s shortens, got %d", want, outcome, got)
+		}
+	}
+}
+
+func TestMetrics_Redirects(t *testing.T) {
+	router, m := setupMetricsRouter()
+	serve(router, "POST", "/api/shorten", `{"url":"https://www.example.com/redirect","alias":"go-here"}`)
+
+	serve(router, "GET", "/go-here", "")
+	serve(router, "HEAD", "/go-here", "")
+	serve(router, "GET", "/missing1", "")
+
+	if got := m.RedirectCount(http.StatusFound); got != 2 {
+		t.Fatalf("Expected 2 redirects with 302, got %d", got)
+	}
+	if got := m.RedirectCount(http.StatusNotFound); got != 1 {
+		t.Fatalf("Expected 1 redirect with 404, got %d", got)
+	}
+}
+
+func TestMetrics_RouteLatency(t *testing.T) {
+	router, m := setupMetricsRouter()
+	serve(router, "POST", "/api/shorten", `{"url":"https://www.example.com/latency","alias":"timed"}`)
+	serve(router, "GET", "/timed", "")
+	serve(router, "GET", "/other1", "")
+
+	if got := m.RequestCount("/{shortCode}", "GET"); got != 2 {
+		t.Fatalf("Expected both redirects timed under one route, got %d", got)
+	}
+
+	w := serve(router, "GET", "/metrics", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != metrics.ContentType {
+		t.Fatalf("Expected the exposition format, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`urlshortener_shorten_requests_total{outcome="created"} 1`,
+		`urlshortener_redirects_total{status="302"} 1`,
+		`urlshortener_redirects_total{status="404"} 1`,
+		`urlshortener_http_request_duration_seconds_count{route="/api/shorten",method="POST"} 1`,
+		`urlshortener_http_request_duration_seconds_bucket{route="/{shortCode}",method="GET",le="+Inf"} 2`,
+		`urlshortener_storage_links 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in the scrape", line)
+		}
+	}
+}
+
+func TestStorage_Len(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range map[string]interface {
+		storage.Storage
+		Len() int
+	}{
+		"memory":  storage.NewMemoryStorage(nil),
+		"sharded": storage.NewShardedMemoryStorage(4, nil),
+	} {
+		store.Save(ctx, storage.Link{ShortCode: "len00001", LongURL: "https://www.example.com/1"})
+		store.Save(ctx, storage.Link{ShortCode: "len00001", LongURL: "https://www.example.com/1"})
+		store.SaveAlias(ctx, storage.Link{ShortCode: "len-alias", LongURL: "https://www.example.com/1"})
+		store.Save(ctx, storage.Link{ShortCode: "len00002", LongURL: "https://www.example.com/2"})
+		store.Update(ctx, "len00002", "https://www.example.com/3")
+		if got := store.Len(); got != 3 {
+			t.Fatalf("%s: expected 3 links, got %d", name, got)
+		}
+
+		store.Delete(ctx, "len00001")
+		store.Delete(ctx, "len00001")
+		if got := store.Len(); got != 2 {
+			t.Fatalf("%s: expected 2 links after delete, got %d", name, got)
+		}
+	}
+}
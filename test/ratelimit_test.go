@@ -0,0 +1,297 @@
+/*
+This file contains unit tests for per-client rate limiting.
+
+- TestParseRate: "N/s", "N/m", "N/h" and "N/<duration>" parse, malformed rates are rejected.
+- TestLimiter_TokenBucket: a client gets Burst requests at once, then one more per refill interval, with Retry-After and Reset reported.
+- TestLimiter_AllowN: n tokens are taken all or none, and more than Burst are never allowed.
+- TestLimiter_BoundedClients: the client table never holds more than MaxClients, dropping the least recently seen.
+- TestLimit_TooManyRequests: over the limit, shorten answers 429 rate_limited with Retry-After and RateLimit-* headers, and the rejection is counted.
+- TestLimit_BatchPerItem: a batch costs a token per item; a JSON batch over the budget answers 429 (413 past the burst) and a streamed batch ends with a rate_limited line.
+- TestLimit_PerKey: each API key has its own bucket, even from one IP.
+- TestLimit_TrustedProxies: X-Forwarded-For picks the client only behind a trusted proxy, and cannot be spoofed from elsewhere.
+*/
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/auth"
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/metrics"
+	"URL_Shortener_Ruckus_Networks/internals/ratelimit"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func newLimiter(t *testing.T, opts ratelimit.Options) *ratelimit.Limiter {
+	l, err := ratelimit.New(opts)
+	if err != nil {
+		t.Fatalf("ratelimit.New failed: %v", err)
+	}
+	return l
+}
+
+// setupLimitRouter limits redirects to 2 per minute per client
+func setupLimitRouter(t *testing.T) (*mux.Router, *handler.Handler, *metrics.Server) {
+	h := handler.NewHandler(service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil), nil, nil)
+	m := metrics.NewServer()
+	h.SetMetrics(m)
+	limit := newLimiter(t, ratelimit.Options{Name: "redirect", Requests: 2, Per: time.Minute})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{shortCode}", h.Limit(limit, h.RedirectURL)).Methods("GET")
+	return router, h, m
+}
+
+func fromAddr(router *mux.Router, target, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestParseRate(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"10/s":   time.Second,
+		"30/m":   time.Minute,
+		"100/h":  time.Hour,
+		"5/30s":  30 * time.Second,
+		" 7 / m": time.Minute,
+	} {
+		if _, per, err := ratelimit.ParseRate(s); err != nil || per != want {
+			t.Errorf("%q: expected per %v, got %v %v", s, want, per, err)
+		}
+	}
+	for _, s := range []string{"", "10", "0/s", "-1/s", "x/s", "10/day", "10/-1s"} {
+		if _, _, err := ratelimit.ParseRate(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	l := newLimiter(t, ratelimit.Options{Requests: 1, Per: time.Second, Burst: 3})
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if d := l.AllowAt("a", now); !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("request %d: expected allowed with %d left, got %+v", i, 2-i, d)
+		}
+	}
+	d := l.AllowAt("a", now)
+	if d.Allowed || d.RetryAfter != time.Second || d.Reset != 3*time.Second || d.Limit != 3 {
+		t.Fatalf("Expected denial with a 1s retry and 3s reset, got %+v", d)
+	}
+	if d := l.AllowAt("b", now); !d.Allowed {
+		t.Fatal("Expected another client to have its own bucket")
+	}
+
+	if d := l.AllowAt("a", now.Add(500*time.Millisecond)); d.Allowed || d.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("Expected denial with half a token refilled, got %+v", d)
+	}
+	if d := l.AllowAt("a", now.Add(time.Second)); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("Expected one token after 1s, got %+v", d)
+	}
+	if d := l.AllowAt("a", now.Add(time.Hour)); !d.Allowed || d.Remaining != 2 {
+		t.Fatalf("Expected refill to stop at Burst, got %+v", d)
+	}
+}
+
+func TestLimiter_AllowN(t *testing.T) {
+	l := newLimiter(t, ratelimit.Options{Requests: 1, Per: time.Second, Burst: 5})
+	now := time.Now()
+
+	if d := l.AllowNAt("a", 3, now); !d.Allowed || d.Remaining != 2 {
+		t.Fatalf("Expected 3 tokens taken with 2 left, got %+v", d)
+	}
+	if d := l.AllowNAt("a", 3, now); d.Allowed || d.Remaining != 2 || d.RetryAfter != time.Second {
+		t.Fatalf("Expected denial taking nothing with a 1s retry, got %+v", d)
+	}
+	if d := l.AllowNAt("a", 2, now); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("Expected the last 2 tokens taken, got %+v", d)
+	}
+	if d := l.AllowNAt("b", 6, now.Add(time.Hour)); d.Allowed {
+		t.Fatalf("Expected more than Burst to be denied, got %+v", d)
+	}
+}
+
+func TestLimiter_BoundedClients(t *testing.T) {
+	l := newLimiter(t, ratelimit.Options{Requests: 1, Per: time.Minute, MaxClients: 2})
+	now := time.Now()
+
+	l.AllowAt("a", now)
+	l.AllowAt("b", now)
+	l.AllowAt("a", now)
+	l.AllowAt("c", now) // drops b, the least recently seen
+	if got := l.Len(); got != 2 {
+		t.Fatalf("Expected 2 clients tracked, got %d", got)
+	}
+	if d := l.AllowAt("a", now); d.Allowed {
+		t.Fatal("Expected a to keep its empty bucket")
+	}
+	if d := l.AllowAt("b", now); !d.Allowed {
+		t.Fatal("Expected dropped client b to start with a full bucket")
+	}
+}
+
+func TestLimit_TooManyRequests(t *testing.T) {
+	h := handler.NewHandler(service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil), nil, nil)
+	m := metrics.NewServer()
+	h.SetMetrics(m)
+	limit := newLimiter(t, ratelimit.Options{Name: "shorten", Requests: 2, Per: time.Minute})
+	router := mux.NewRouter()
+	router.HandleFunc("/api/shorten", h.Limit(limit, h.ShortenURL)).Methods("POST")
+
+	shorten := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://www.example.com/limited"}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := shorten(); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("Expected 200 with RateLimit headers, got %d %v", w.Code, w.Header())
+	}
+	shorten()
+
+	w := shorten()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Reset") != "60" {
+		t.Fatalf("Unexpected rate limit headers %v", w.Header())
+	}
+	if code := errorCode(t, w); code != handler.CodeRateLimited {
+		t.Fatalf("Expected code %s, got %s", handler.CodeRateLimited, code)
+	}
+	if got := m.RateLimitedCount("shorten"); got != 1 {
+		t.Fatalf("Expected 1 rejection counted, got %d", got)
+	}
+}
+
+func TestLimit_BatchPerItem(t *testing.T) {
+	h := handler.NewHandler(service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil), nil, nil)
+	m := metrics.NewServer()
+	h.SetMetrics(m)
+	limit := newLimiter(t, ratelimit.Options{Name: "shorten", Requests: 6, Per: time.Minute})
+	router := mux.NewRouter()
+	router.HandleFunc("/api/shorten/batch", h.Limit(limit, h.ShortenBatch)).Methods("POST")
+
+	batch := func(contentType string, urls ...string) *httptest.ResponseRecorder {
+		var body strings.Builder
+		for i, url := range urls {
+			if contentType == "application/json" && i > 0 {
+				body.WriteString(",")
+			}
+			body.WriteString(`{"url":"` + url + `"}`)
+			if contentType == "application/x-ndjson" {
+				body.WriteString("\n")
+			}
+		}
+		text := body.String()
+		if contentType == "application/json" {
+			text = "[" + text + "]"
+		}
+		req := httptest.NewRequest("POST", "/api/shorten/batch", strings.NewReader(text))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 7 items can never fit a burst of 6; the refusal keeps all but the request's token
+	if w := batch("application/json", strings.Fields(strings.Repeat("https://www.example.com/x ", 7))...); w.Code != http.StatusRequestEntityTooLarge || errorCode(t, w) != handler.CodeBatchTooLarge {
+		t.Fatalf("Expected 413 for a batch past the burst, got %d", w.Code)
+	}
+	if w := batch("application/json", "https://www.example.com/1", "https://www.example.com/2", "https://www.example.com/3"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "2" {
+		t.Fatalf("Expected 200 with 3 tokens taken, got %d %v", w.Code, w.Header())
+	}
+	if w := batch("application/json", "https://www.example.com/4", "https://www.example.com/5", "https://www.example.com/6"); w.Code != http.StatusTooManyRequests || errorCode(t, w) != handler.CodeRateLimited || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 for 3 items with 2 tokens left, got %d %v", w.Code, w.Header())
+	}
+
+	// the 429 above took only the request's token, leaving 1 for the stream
+	w := batch("application/x-ndjson", "https://www.example.com/7", "https://www.example.com/8", "https://www.example.com/9")
+	var results []handler.BatchResult
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		var result handler.BatchResult
+		json.Unmarshal([]byte(line), &result)
+		results = append(results, result)
+	}
+	if len(results) != 2 || results[0].ShortURL == "" || results[1].Code != handler.CodeRateLimited {
+		t.Fatalf("Expected one result and then a rate_limited line, got %s", w.Body.String())
+	}
+	if got := m.RateLimitedCount("shorten"); got != 3 {
+		t.Fatalf("Expected 3 rejections counted, got %d", got)
+	}
+}
+
+func TestLimit_PerKey(t *testing.T) {
+	h := handler.NewHandler(service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil), nil, nil)
+	keys, _ := auth.NewMemoryKeyStore()
+	raws := make([]string, 2)
+	for i, id := range []string{"one", "two"} {
+		raw, hash, _ := auth.GenerateKey()
+		keys.Add(auth.Key{ID: id, Hash: hash, Scopes: []auth.Scope{auth.ScopeRead}})
+		raws[i] = raw
+	}
+	h.SetKeyStore(keys)
+	limit := newLimiter(t, ratelimit.Options{Name: "links", Requests: 1, Per: time.Minute})
+
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(h.Authenticate)
+	api.HandleFunc("/links", h.Limit(limit, h.Require(auth.ScopeRead, h.ListLinks))).Methods("GET")
+
+	if w := bearer(router, "GET", "/api/links", "", raws[0]); w.Code != http.StatusOK {
+		t.Fatalf("Expected first key's request to pass, got %d", w.Code)
+	}
+	if w := bearer(router, "GET", "/api/links", "", raws[0]); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected first key to be limited, got %d", w.Code)
+	}
+	if w := bearer(router, "GET", "/api/links", "", raws[1]); w.Code != http.StatusOK {
+		t.Fatalf("Expected second key from the same IP to have its own bucket, got %d", w.Code)
+	}
+}
+
+func TestLimit_TrustedProxies(t *testing.T) {
+	router, h, m := setupLimitRouter(t)
+	h.SetTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+
+	// untrusted peer: X-Forwarded-For is ignored, so rotating it does not help
+	for i, xff := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		w := fromAddr(router, "/missing1", "203.0.113.9:5000", xff)
+		if i < 2 && w.Code != http.StatusNotFound {
+			t.Fatalf("request %d: expected 404, got %d", i, w.Code)
+		}
+		if i == 2 && w.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected spoofed X-Forwarded-For to be ignored, got %d", w.Code)
+		}
+	}
+
+	// behind two trusted proxies the first untrusted hop from the right is the client
+	for i := 0; i < 2; i++ {
+		fromAddr(router, "/missing1", "10.0.0.1:5000", "198.51.100.7, 10.1.1.1")
+	}
+	if w := fromAddr(router, "/missing1", "10.0.0.1:5000", "6.6.6.6, 198.51.100.7, 10.1.1.1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected the client left of the proxies to be limited, got %d", w.Code)
+	}
+	if w := fromAddr(router, "/missing1", "10.0.0.1:5000", "198.51.100.8"); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected another client behind the proxy to have its own bucket, got %d", w.Code)
+	}
+	if got := m.RateLimitedCount("redirect"); got != 2 {
+		t.Fatalf("Expected 2 rejections counted, got %d", got)
+	}
+}
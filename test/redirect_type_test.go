@@ -0,0 +1,172 @@
+/*
+This file contains unit tests for configurable redirect statuses.
+
+- TestShortenURL_RedirectType: each of 301, 302, 307 and 308 is stored with the link, echoed in the response and used by its redirect, with Cache-Control public for permanent and no-store for temporary redirects.
+- TestShortenURL_RedirectTypeInvalid: any other status yields 400 with the invalid_redirect_type error code.
+- TestRedirect_ServerDefault: links without a redirect_type follow SetRedirectStatus, links with one keep theirs, and redirects are counted by the status sent.
+- TestRedirect_PermanentMaxAge: a permanent redirect is cached for SetPermanentMaxAge, or only until the link expires when that is sooner.
+- TestShorten_RedirectTypeKept: shortening an existing URL without redirect_type keeps the link's status and reports it in the response, with one it changes it, and link metadata reports it.
+*/
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/metrics"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func setupRedirectRouter() (*mux.Router, *handler.Handler, *service.URLService) {
+	svc := service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil)
+	h := handler.NewHandler(svc, nil, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/links/{shortCode}", h.GetLink).Methods("GET")
+	router.HandleFunc("/{shortCode}", h.RedirectURL).Methods("GET", "HEAD")
+	return router, h, svc
+}
+
+// shortenWithRedirect shortens url with redirectType and returns the response and code
+func shortenWithRedirect(t *testing.T, h *handler.Handler, url string, redirectType int) (handler.ShortenResponse, string) {
+	t.Helper()
+	w := postShorten(h, handler.ShortenRequest{URL: url, RedirectType: redirectType})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for redirect_type %d, got %d: %s", redirectType, w.Code, w.Body.String())
+	}
+	var resp handler.ShortenResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return resp, path.Base(resp.ShortURL)
+}
+
+func redirect(router *mux.Router, code string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/"+code, nil))
+	return w
+}
+
+func TestShortenURL_RedirectType(t *testing.T) {
+	router, h, _ := setupRedirectRouter()
+
+	for status, cacheControl := range map[int]string{
+		http.StatusMovedPermanently:  "public, max-age=86400",
+		http.StatusFound:             "no-store",
+		http.StatusTemporaryRedirect: "no-store",
+		http.StatusPermanentRedirect: "public, max-age=86400",
+	} {
+		resp, code := shortenWithRedirect(t, h, "https://www.example.com/"+strconv.Itoa(status), status)
+		if resp.RedirectType != status {
+			t.Errorf("%d: expected redirect_type echoed, got %d", status, resp.RedirectType)
+		}
+
+		w := redirect(router, code)
+		if w.Code != status || w.Header().Get("Location") != "https://www.example.com/"+strconv.Itoa(status) {
+			t.Errorf("%d: expected a %d redirect, got %d %s", status, status, w.Code, w.Header().Get("Location"))
+		}
+		if got := w.Header().Get("Cache-Control"); got != cacheControl {
+			t.Errorf("%d: expected Cache-Control %q, got %q", status, cacheControl, got)
+		}
+	}
+}
+
+func TestShortenURL_RedirectTypeInvalid(t *testing.T) {
+	_, h, _ := setupRedirectRouter()
+
+	for _, status := range []int{200, 300, 303, 304, 404, -301} {
+		w := postShorten(h, handler.ShortenRequest{URL: "https://www.example.com/x", RedirectType: status})
+		if w.Code != http.StatusBadRequest || errorCode(t, w) != handler.CodeInvalidRedirect {
+			t.Errorf("%d: expected 400 invalid_redirect_type, got %d", status, w.Code)
+		}
+	}
+}
+
+func TestRedirect_ServerDefault(t *testing.T) {
+	router, h, _ := setupRedirectRouter()
+	m := metrics.NewServer()
+	h.SetMetrics(m)
+	h.SetRedirectStatus(http.StatusPermanentRedirect)
+
+	_, plain := shortenWithRedirect(t, h, "https://www.example.com/plain", 0)
+	_, temporary := shortenWithRedirect(t, h, "https://www.example.com/temporary", http.StatusFound)
+
+	if w := redirect(router, plain); w.Code != http.StatusPermanentRedirect || !strings.HasPrefix(w.Header().Get("Cache-Control"), "public") {
+		t.Fatalf("Expected the server default 308, got %d %s", w.Code, w.Header().Get("Cache-Control"))
+	}
+	if w := redirect(router, temporary); w.Code != http.StatusFound || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("Expected the link's own 302, got %d %s", w.Code, w.Header().Get("Cache-Control"))
+	}
+	redirect(router, "missing1")
+
+	if m.RedirectCount(http.StatusPermanentRedirect) != 1 || m.RedirectCount(http.StatusFound) != 1 || m.RedirectCount(http.StatusNotFound) != 1 {
+		t.Fatalf("Expected one 308, one 302 and one 404 counted")
+	}
+}
+
+func TestRedirect_PermanentMaxAge(t *testing.T) {
+	router, h, svc := setupRedirectRouter()
+	h.SetPermanentMaxAge(time.Hour)
+	ctx := context.Background()
+
+	_, forever := shortenWithRedirect(t, h, "https://www.example.com/forever", http.StatusMovedPermanently)
+	if got := redirect(router, forever).Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Fatalf("Expected the configured max-age, got %q", got)
+	}
+
+	_, code, err := svc.Shorten(ctx, "https://www.example.com/soon", service.ShortenOptions{
+		ExpiresAt:      time.Now().Add(10 * time.Minute),
+		RedirectStatus: http.StatusMovedPermanently,
+	})
+	if err != nil {
+		t.Fatalf("Shorten failed: %v", err)
+	}
+	cacheControl := redirect(router, code).Header().Get("Cache-Control")
+	maxAge, err := strconv.Atoi(strings.TrimPrefix(cacheControl, "public, max-age="))
+	if err != nil || maxAge <= 0 || maxAge > 600 {
+		t.Fatalf("Expected max-age capped at the link's expiry, got %q", cacheControl)
+	}
+}
+
+func TestShorten_RedirectTypeKept(t *testing.T) {
+	router, h, svc := setupRedirectRouter()
+	ctx := context.Background()
+
+	_, code := shortenWithRedirect(t, h, "https://www.example.com/kept", http.StatusMovedPermanently)
+	if resp, _ := shortenWithRedirect(t, h, "https://www.example.com/kept", 0); resp.RedirectType != http.StatusMovedPermanently {
+		t.Fatalf("Expected the response to report the stored 301, got %d", resp.RedirectType)
+	}
+	if w := redirect(router, code); w.Code != http.StatusMovedPermanently {
+		t.Fatalf("Expected the link to keep 301, got %d", w.Code)
+	}
+
+	shortenWithRedirect(t, h, "https://www.example.com/kept", http.StatusTemporaryRedirect)
+	if w := redirect(router, code); w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("Expected the link to move to 307, got %d", w.Code)
+	}
+
+	svc.Shorten(ctx, "https://www.example.com/alias", service.ShortenOptions{Alias: "vanity", RedirectStatus: http.StatusPermanentRedirect})
+	if result, _ := svc.ShortenLink(ctx, "https://www.example.com/alias", service.ShortenOptions{Alias: "vanity"}); result.RedirectStatus != http.StatusPermanentRedirect {
+		t.Fatalf("Expected the result to report the alias's 308, got %d", result.RedirectStatus)
+	}
+	if w := redirect(router, "vanity"); w.Code != http.StatusPermanentRedirect {
+		t.Fatalf("Expected the alias to keep 308, got %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/links/"+code, nil))
+	var resp handler.LinkResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.RedirectType != http.StatusTemporaryRedirect {
+		t.Fatalf("Expected redirect_type 307 in link metadata, got %d", resp.RedirectType)
+	}
+}
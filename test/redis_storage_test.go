@@ -0,0 +1,269 @@
+/*
+This file contains unit tests for the Redis storage backend, run against an in-process miniredis server.
+
+- TestRedisStorage_SharedAcrossReplicas: a link saved through one client is visible, with its reverse lookup, through a second client on the same server.
+- TestRedisStorage_KeyPrefix: every key is written under the configured prefix and stores with different prefixes do not see each other.
+- TestRedisStorage_Collision: saving a code held by a different URL returns ErrAlreadyExists and leaves both mappings untouched.
+- TestRedisStorage_AliasUpdateDelete: aliases skip the reverse lookup, Update moves it and Delete removes it, with ErrNotFound for unknown codes.
+- TestRedisStorage_NativeTTL: an expired link answers ErrExpired during the retention window and is then dropped by Redis itself.
+- TestRedisStorage_PurgeExpired: the sweep reclaims only links due in the expiries set, tidies index entries of links Redis already dropped, and leaves live links alone.
+- TestRedisStorage_List: listing pages through links in creation order with filters, matching the memory backend.
+- TestRedisStorage_Service: the URL service behaves the same on Redis as on the memory store, including collision resolution.
+*/
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+func openRedisStorage(t *testing.T, mr *miniredis.Miniredis, prefix string) *storage.RedisStorage {
+	t.Helper()
+	opts := storage.DefaultRedisOptions()
+	opts.Addr = mr.Addr()
+	opts.Prefix = prefix
+
+	store, err := storage.NewRedisStorage(opts)
+	if err != nil {
+		t.Fatalf("Failed to open redis storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestRedisStorage_SharedAcrossReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	first := openRedisStorage(t, mr, "test:")
+	second := openRedisStorage(t, mr, "test:")
+
+	if err := first.Save(context.Background(), storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/shared"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	longURL, err := second.GetLongURL(context.Background(), "abc12345")
+	if err != nil || longURL != "https://www.example.com/shared" {
+		t.Fatalf("Expected link through second client, got %s, %v", longURL, err)
+	}
+	shortCode, err := second.GetShortCode(context.Background(), "https://www.example.com/shared")
+	if err != nil || shortCode != "abc12345" {
+		t.Fatalf("Expected reverse mapping through second client, got %s, %v", shortCode, err)
+	}
+}
+
+func TestRedisStorage_KeyPrefix(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := openRedisStorage(t, mr, "a:")
+	b := openRedisStorage(t, mr, "b:")
+
+	a.Save(context.Background(), storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/a"})
+
+	for _, key := range mr.Keys() {
+		if !strings.HasPrefix(key, "a:") {
+			t.Fatalf("Expected every key under the a: prefix, found %s", key)
+		}
+	}
+	if b.Exists(context.Background(), "abc12345") {
+		t.Fatalf("Expected store with another prefix not to see the link")
+	}
+	if err := b.Save(context.Background(), storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/b"}); err != nil {
+		t.Fatalf("Expected same code under another prefix to be free, got %v", err)
+	}
+}
+
+func TestRedisStorage_Collision(t *testing.T) {
+	store := openRedisStorage(t, miniredis.RunT(t), "test:")
+
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("First save failed: %v", err)
+	}
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("Expected identical save to succeed, got %v", err)
+	}
+
+	err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/b"})
+	if err != storage.ErrAlreadyExists {
+		t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	longURL, _ := store.GetLongURL(context.Background(), "samecode")
+	if longURL != "https://www.example.com/a" {
+		t.Fatalf("Expected original mapping to survive, got %s", longURL)
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/b"); err != storage.ErrNotFound {
+		t.Fatalf("Expected no reverse mapping for rejected URL, got %v", err)
+	}
+}
+
+func TestRedisStorage_AliasUpdateDelete(t *testing.T) {
+	store := openRedisStorage(t, miniredis.RunT(t), "test:")
+
+	store.Save(context.Background(), storage.Link{ShortCode: "hash0001", LongURL: "https://www.example.com/a"})
+	if err := store.SaveAlias(context.Background(), storage.Link{ShortCode: "my-alias", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("SaveAlias failed: %v", err)
+	}
+	if code, _ := store.GetShortCode(context.Background(), "https://www.example.com/a"); code != "hash0001" {
+		t.Fatalf("Expected alias to leave the reverse lookup alone, got %s", code)
+	}
+
+	if err := store.Update(context.Background(), "hash0001", "https://www.example.com/b"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/a"); err != storage.ErrNotFound {
+		t.Fatalf("Expected old URL to lose its reverse lookup, got %v", err)
+	}
+	if code, _ := store.GetShortCode(context.Background(), "https://www.example.com/b"); code != "hash0001" {
+		t.Fatalf("Expected reverse lookup to follow the update, got %s", code)
+	}
+
+	if err := store.Delete(context.Background(), "hash0001"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if store.Exists(context.Background(), "hash0001") {
+		t.Fatalf("Expected deleted code to be gone")
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/b"); err != storage.ErrNotFound {
+		t.Fatalf("Expected reverse lookup to be removed, got %v", err)
+	}
+
+	if err := store.Update(context.Background(), "missing1", "https://www.example.com/c"); err != storage.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound from Update, got %v", err)
+	}
+	if err := store.Delete(context.Background(), "missing1"); err != storage.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound from Delete, got %v", err)
+	}
+}
+
+func TestRedisStorage_NativeTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	opts := storage.DefaultRedisOptions()
+	opts.Addr = mr.Addr()
+	opts.ExpiredRetention = time.Hour
+	store, err := storage.NewRedisStorage(opts)
+	if err != nil {
+		t.Fatalf("Failed to open redis storage: %v", err)
+	}
+	defer store.Close()
+
+	store.Save(context.Background(), storage.Link{ShortCode: "expired1", LongURL: "https://www.example.com/old", ExpiresAt: time.Now().Add(-time.Second)})
+	store.Save(context.Background(), storage.Link{ShortCode: "live0001", LongURL: "https://www.example.com/live"})
+
+	if _, err := store.GetLongURL(context.Background(), "expired1"); err != storage.ErrExpired {
+		t.Fatalf("Expected ErrExpired during retention, got %v", err)
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/old"); err != storage.ErrNotFound {
+		t.Fatalf("Expected expired link to be ignored by reverse lookup, got %v", err)
+	}
+	if ttl := mr.TTL(opts.Prefix + "link:expired1"); ttl <= 0 {
+		t.Fatalf("Expected a native TTL on the expired link, got %v", ttl)
+	}
+	if ttl := mr.TTL(opts.Prefix + "link:live0001"); ttl != 0 {
+		t.Fatalf("Expected no TTL on a link without expiry, got %v", ttl)
+	}
+
+	mr.FastForward(time.Hour)
+
+	if _, err := store.GetLink(context.Background(), "expired1"); err != storage.ErrNotFound {
+		t.Fatalf("Expected Redis to drop the expired link, got %v", err)
+	}
+	if !store.Exists(context.Background(), "live0001") {
+		t.Fatalf("Expected live link to survive")
+	}
+
+	page, _ := store.List(context.Background(), storage.ListOptions{})
+	if len(page.Links) != 1 || page.Links[0].ShortCode != "live0001" {
+		t.Fatalf("Expected listing to skip the dropped link, got %+v", page.Links)
+	}
+}
+
+func TestRedisStorage_PurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	store := openRedisStorage(t, mr, "test:")
+
+	store.Save(ctx, storage.Link{ShortCode: "dropped1", LongURL: "https://www.example.com/dropped", ExpiresAt: time.Now().Add(-time.Second)})
+	mr.FastForward(time.Hour) // Redis drops dropped1 through its native TTL
+	store.Save(ctx, storage.Link{ShortCode: "expired1", LongURL: "https://www.example.com/old", ExpiresAt: time.Now().Add(-time.Second)})
+	store.Save(ctx, storage.Link{ShortCode: "later001", LongURL: "https://www.example.com/later", ExpiresAt: time.Now().Add(time.Hour)})
+	store.Save(ctx, storage.Link{ShortCode: "forever1", LongURL: "https://www.example.com/forever"})
+
+	if purged := store.PurgeExpired(); purged != 1 {
+		t.Fatalf("Expected 1 link reclaimed, got %d", purged)
+	}
+	if _, err := store.GetLink(ctx, "expired1"); err != storage.ErrNotFound {
+		t.Fatalf("Expected the expired link reclaimed, got %v", err)
+	}
+	if mr.Exists("test:url:https://www.example.com/old") {
+		t.Fatal("Expected the expired link's reverse lookup reclaimed")
+	}
+	if !store.Exists(ctx, "later001") || !store.Exists(ctx, "forever1") {
+		t.Fatal("Expected live links to survive the sweep")
+	}
+
+	index, _ := mr.ZMembers("test:links")
+	expiries, _ := mr.ZMembers("test:expiries")
+	if len(index) != 2 || len(expiries) != 1 || !strings.HasSuffix(expiries[0], ":later001") {
+		t.Fatalf("Expected only live links indexed and later001 due to expire, got %v and %v", index, expiries)
+	}
+}
+
+func TestRedisStorage_List(t *testing.T) {
+	redisStore := openRedisStorage(t, miniredis.RunT(t), "test:")
+	memStore := storage.NewMemoryStorage(nil)
+
+	base := time.Now()
+	for i, host := range []string{"a.example.com", "b.example.com", "a.example.com", "c.example.com", "a.example.com"} {
+		link := storage.Link{
+			ShortCode: string(rune('e'-i)) + "code",
+			LongURL:   "https://" + host + "/" + string(rune('a'+i)),
+			CreatedAt: base.Add(time.Duration(i%3) * time.Second),
+		}
+		redisStore.Save(context.Background(), link)
+		memStore.Save(context.Background(), link)
+	}
+
+	for _, opts := range []storage.ListOptions{{Limit: 2}, {Limit: 1, Host: "A.example.com"}, {Limit: 10, Query: "CODE"}} {
+		got := collectPages(t, redisStore, opts)
+		want := collectPages(t, memStore, opts)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("Expected listing %v for %+v, got %v", want, opts, got)
+		}
+	}
+
+	if _, err := redisStore.List(context.Background(), storage.ListOptions{Cursor: "%%%"}); err != storage.ErrInvalidCursor {
+		t.Fatalf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestRedisStorage_Service(t *testing.T) {
+	store := openRedisStorage(t, miniredis.RunT(t), "test:")
+	svc := service.NewURLService(store, "http://localhost:8080", service.CodeGeneratorFunc(collidingGenerator), nil)
+
+	_, first, err := svc.ShortenURL(context.Background(), "https://www.example.com/first")
+	if err != nil {
+		t.Fatalf("First shorten failed: %v", err)
+	}
+	_, second, err := svc.ShortenURL(context.Background(), "https://www.example.com/second")
+	if err != nil {
+		t.Fatalf("Second shorten failed: %v", err)
+	}
+	if first == second {
+		t.Fatalf("Expected collision to be resolved, both got %s", first)
+	}
+
+	_, again, _ := svc.ShortenURL(context.Background(), "https://www.example.com/second")
+	if again != second {
+		t.Fatalf("Expected idempotent shortening, got %s and %s", second, again)
+	}
+
+	longURL, err := svc.GetLongURL(context.Background(), first)
+	if err != nil || longURL != "https://www.example.com/first" {
+		t.Fatalf("Expected first link to keep its destination, got %s, %v", longURL, err)
+	}
+}
@@ -13,6 +13,7 @@ The tests cover the following aspects:
 - Retrieval of original URLs from short codes, including correct handling of non-existent short codes and expected error responses.
 - Deterministic and unique short code generation, ensuring that the same URL always produces the same code, and different URLs produce different codes.
 - Performance benchmarks for both the shortening and retrieval operations, providing insights into the efficiency of the service under repeated use.
+- Parallel benchmarks (b.RunParallel at 8 goroutines per CPU) comparing MemoryStorage with ShardedMemoryStorage for redirects, shortening, and redirects during a write burst.
 
 These tests ensure the correctness, reliability, and performance of the URLService, which is responsible for the main business logic of the URL shortener application.
 */
@@ -20,7 +21,11 @@ These tests ensure the correctness, reliability, and performance of the URLServi
 package test
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"URL_Shortener_Ruckus_Networks/internals/service"
@@ -28,11 +33,11 @@ import (
 )
 
 func TestService_ShortURL_Success(t *testing.T) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 
 	longURL := "https://www.example.com/very/long/url/path"
-	shortURL, shortCode, err := svc.ShortenURL(longURL)
+	shortURL, shortCode, err := svc.ShortenURL(context.Background(), longURL)
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -52,19 +57,19 @@ func TestService_ShortURL_Success(t *testing.T) {
 }
 
 func TestService_ShortURL_Idempotency(t *testing.T) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 
 	longURL := "https://www.example.com/test"
 
 	// First call
-	shortURL1, shortCode1, err1 := svc.ShortenURL(longURL)
+	shortURL1, shortCode1, err1 := svc.ShortenURL(context.Background(), longURL)
 	if err1 != nil {
 		t.Fatalf("First call failed: %v", err1)
 	}
 
 	// Second call with same URL
-	shortURL2, shortCode2, err2 := svc.ShortenURL(longURL)
+	shortURL2, shortCode2, err2 := svc.ShortenURL(context.Background(), longURL)
 	if err2 != nil {
 		t.Fatalf("Second call failed: %v", err2)
 	}
@@ -80,8 +85,8 @@ func TestService_ShortURL_Idempotency(t *testing.T) {
 }
 
 func TestService_ShortURL_InvalidURL(t *testing.T) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 
 	testCases := []struct {
 		name    string
@@ -97,7 +102,7 @@ func TestService_ShortURL_InvalidURL(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			_, _, err := svc.ShortenURL(tc.url)
+			_, _, err := svc.ShortenURL(context.Background(), tc.url)
 			if (err != nil) != tc.wantErr {
 				t.Fatalf("Expected error: %v, got: %v", tc.wantErr, err)
 			}
@@ -106,16 +111,16 @@ func TestService_ShortURL_InvalidURL(t *testing.T) {
 }
 
 func TestGetLongURL_Success(t *testing.T) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 
 	longURL := "https://www.example.com/test"
-	_, shortCode, err := svc.ShortenURL(longURL)
+	_, shortCode, err := svc.ShortenURL(context.Background(), longURL)
 	if err != nil {
 		t.Fatalf("Failed to shorten URL: %v", err)
 	}
 
-	retrievedURL, err := svc.GetLongURL(shortCode)
+	retrievedURL, err := svc.GetLongURL(context.Background(), shortCode)
 	if err != nil {
 		t.Fatalf("Failed to get long URL: %v", err)
 	}
@@ -126,10 +131,10 @@ func TestGetLongURL_Success(t *testing.T) {
 }
 
 func TestGetLongURL_NotFound(t *testing.T) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 
-	_, err := svc.GetLongURL("nonexistent")
+	_, err := svc.GetLongURL(context.Background(), "nonexistent")
 	if err == nil {
 		t.Fatal("Expected error for non-existent short code")
 	}
@@ -140,8 +145,8 @@ func TestGetLongURL_NotFound(t *testing.T) {
 }
 
 func TestGenerateShortCode_Deterministic(t *testing.T) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 
 	url := "https://www.example.com/test"
 
@@ -154,8 +159,8 @@ func TestGenerateShortCode_Deterministic(t *testing.T) {
 }
 
 func TestGenerateShortCode_Unique(t *testing.T) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 
 	url1 := "https://www.example.com/test1"
 	url2 := "https://www.example.com/test2"
@@ -169,13 +174,13 @@ func TestGenerateShortCode_Unique(t *testing.T) {
 }
 
 func BenchmarkShortenURL(b *testing.B) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 	longURL := "https://www.example.com/benchmark"
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _, err := svc.ShortenURL(longURL)
+		_, _, err := svc.ShortenURL(context.Background(), longURL)
 		if err != nil {
 			b.Fatalf("ShortenURL failed: %v", err)
 		}
@@ -183,19 +188,125 @@ func BenchmarkShortenURL(b *testing.B) {
 }
 
 func BenchmarkGetLongURL(b *testing.B) {
-	store := storage.NewMemoryStorage()
-	svc := service.NewURLService(store, "http://localhost:8080")
+	store := storage.NewMemoryStorage(nil)
+	svc := service.NewURLService(store, "http://localhost:8080", nil, nil)
 	longURL := "https://www.example.com/benchmark"
-	_, shortCode, err := svc.ShortenURL(longURL)
+	_, shortCode, err := svc.ShortenURL(context.Background(), longURL)
 	if err != nil {
 		b.Fatalf("ShortenURL failed: %v", err)
 	}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := svc.GetLongURL(shortCode)
+		_, err := svc.GetLongURL(context.Background(), shortCode)
 		if err != nil {
 			b.Fatalf("GetLongURL failed: %v", err)
 		}
 	}
 }
+
+// storages compared by the parallel benchmarks
+var parallelStores = []struct {
+	name string
+	new  func() storage.Storage
+}{
+	{"memory", func() storage.Storage { return storage.NewMemoryStorage(nil) }},
+	{"sharded", func() storage.Storage { return storage.NewShardedMemoryStorage(storage.DefaultShardCount, nil) }},
+}
+
+// parallel benchmarks measure lock contention, not log output
+func discardLogs(b *testing.B) {
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	b.Cleanup(func() { slog.SetDefault(prev) })
+}
+
+// seedLinks shortens n distinct URLs and returns their codes
+func seedLinks(b *testing.B, svc *service.URLService, n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		_, code, err := svc.ShortenURL(context.Background(), fmt.Sprintf("https://www.example.com/seed/%d", i))
+		if err != nil {
+			b.Fatalf("ShortenURL failed: %v", err)
+		}
+		codes[i] = code
+	}
+	return codes
+}
+
+func BenchmarkGetLongURL_Parallel(b *testing.B) {
+	discardLogs(b)
+	for _, s := range parallelStores {
+		b.Run(s.name, func(b *testing.B) {
+			svc := service.NewURLService(s.new(), "http://localhost:8080", nil, nil)
+			codes := seedLinks(b, svc, 1024)
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				for i := 0; pb.Next(); i++ {
+					if _, err := svc.GetLongURL(ctx, codes[i%len(codes)]); err != nil {
+						b.Errorf("GetLongURL failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkShortenURL_Parallel(b *testing.B) {
+	discardLogs(b)
+	for _, s := range parallelStores {
+		b.Run(s.name, func(b *testing.B) {
+			svc := service.NewURLService(s.new(), "http://localhost:8080", nil, nil)
+			var next atomic.Uint64
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				for pb.Next() {
+					longURL := fmt.Sprintf("https://www.example.com/parallel/%d", next.Add(1))
+					if _, _, err := svc.ShortenURL(ctx, longURL); err != nil {
+						b.Errorf("ShortenURL failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// redirects while one in ten operations is a new link, as during a bulk import
+func BenchmarkMixed_Parallel(b *testing.B) {
+	discardLogs(b)
+	for _, s := range parallelStores {
+		b.Run(s.name, func(b *testing.B) {
+			svc := service.NewURLService(s.new(), "http://localhost:8080", nil, nil)
+			codes := seedLinks(b, svc, 1024)
+			var next atomic.Uint64
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				ctx := context.Background()
+				for i := 0; pb.Next(); i++ {
+					if i%10 == 0 {
+						longURL := fmt.Sprintf("https://www.example.com/mixed/%d", next.Add(1))
+						if _, _, err := svc.ShortenURL(ctx, longURL); err != nil {
+							b.Errorf("ShortenURL failed: %v", err)
+							return
+						}
+						continue
+					}
+					if _, err := svc.GetLongURL(ctx, codes[i%len(codes)]); err != nil {
+						b.Errorf("GetLongURL failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,244 @@
+/*
+This file contains unit tests for the SQLite storage backend.
+
+- TestSQLiteStorage_Migrations: a new database is migrated to the latest schema and reopening it applies nothing twice.
+- TestSQLiteStorage_PersistsAcrossReopen: links, reverse lookups and expiries saved before Close are available after reopening the same file.
+- TestSQLiteStorage_Collision: saving a code held by a different URL returns ErrAlreadyExists and leaves both mappings untouched.
+- TestSQLiteStorage_AliasUpdateDelete: aliases skip the reverse lookup, Update moves it and Delete removes it, with ErrNotFound for unknown codes.
+- TestSQLiteStorage_Expiry: expired links report ErrExpired, give up their code and are reclaimed by PurgeExpired.
+- TestSQLiteStorage_List: listing pages through links in creation order with filters, matching the memory backend.
+- TestSQLiteStorage_Service: the URL service behaves the same on SQLite as on the memory store, including collision resolution.
+*/
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+)
+
+func openSQLiteStorage(t *testing.T, path string) *storage.SQLiteStorage {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(path, nil)
+	if err != nil {
+		t.Fatalf("Failed to open sqlite storage: %v", err)
+	}
+	return store
+}
+
+func TestSQLiteStorage_Migrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.db")
+
+	store := openSQLiteStorage(t, path)
+	version, err := store.SchemaVersion()
+	if err != nil || version < 1 {
+		t.Fatalf("Expected migrated schema, got version %d, %v", version, err)
+	}
+	store.Close()
+
+	store = openSQLiteStorage(t, path)
+	defer store.Close()
+	if again, _ := store.SchemaVersion(); again != version {
+		t.Fatalf("Expected schema version to stay at %d, got %d", version, again)
+	}
+}
+
+func TestSQLiteStorage_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.db")
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	store := openSQLiteStorage(t, path)
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "abc12345", LongURL: "https://www.example.com/persist", ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store = openSQLiteStorage(t, path)
+	defer store.Close()
+
+	link, err := store.GetLink(context.Background(), "abc12345")
+	if err != nil {
+		t.Fatalf("Expected link after reopen, got %v", err)
+	}
+	if link.LongURL != "https://www.example.com/persist" || !link.ExpiresAt.Equal(expiresAt) || link.CreatedAt.IsZero() {
+		t.Fatalf("Unexpected link after reopen: %+v", link)
+	}
+
+	shortCode, err := store.GetShortCode(context.Background(), "https://www.example.com/persist")
+	if err != nil || shortCode != "abc12345" {
+		t.Fatalf("Expected reverse mapping after reopen, got %s, %v", shortCode, err)
+	}
+}
+
+func TestSQLiteStorage_Collision(t *testing.T) {
+	store := openSQLiteStorage(t, ":memory:")
+	defer store.Close()
+
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("First save failed: %v", err)
+	}
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("Expected identical save to succeed, got %v", err)
+	}
+
+	err := store.Save(context.Background(), storage.Link{ShortCode: "samecode", LongURL: "https://www.example.com/b"})
+	if err != storage.ErrAlreadyExists {
+		t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	longURL, _ := store.GetLongURL(context.Background(), "samecode")
+	if longURL != "https://www.example.com/a" {
+		t.Fatalf("Expected original mapping to survive, got %s", longURL)
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/b"); err != storage.ErrNotFound {
+		t.Fatalf("Expected no reverse mapping for rejected URL, got %v", err)
+	}
+}
+
+func TestSQLiteStorage_AliasUpdateDelete(t *testing.T) {
+	store := openSQLiteStorage(t, ":memory:")
+	defer store.Close()
+
+	store.Save(context.Background(), storage.Link{ShortCode: "hash0001", LongURL: "https://www.example.com/a"})
+	if err := store.SaveAlias(context.Background(), storage.Link{ShortCode: "my-alias", LongURL: "https://www.example.com/a"}); err != nil {
+		t.Fatalf("SaveAlias failed: %v", err)
+	}
+	if code, _ := store.GetShortCode(context.Background(), "https://www.example.com/a"); code != "hash0001" {
+		t.Fatalf("Expected alias to leave the reverse lookup alone, got %s", code)
+	}
+
+	if err := store.Update(context.Background(), "hash0001", "https://www.example.com/b"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/a"); err != storage.ErrNotFound {
+		t.Fatalf("Expected old URL to lose its reverse lookup, got %v", err)
+	}
+	if code, _ := store.GetShortCode(context.Background(), "https://www.example.com/b"); code != "hash0001" {
+		t.Fatalf("Expected reverse lookup to follow the update, got %s", code)
+	}
+
+	if err := store.Delete(context.Background(), "hash0001"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if store.Exists(context.Background(), "hash0001") {
+		t.Fatalf("Expected deleted code to be gone")
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/b"); err != storage.ErrNotFound {
+		t.Fatalf("Expected reverse lookup to be removed, got %v", err)
+	}
+
+	if err := store.Update(context.Background(), "missing1", "https://www.example.com/c"); err != storage.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound from Update, got %v", err)
+	}
+	if err := store.Delete(context.Background(), "missing1"); err != storage.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound from Delete, got %v", err)
+	}
+	if _, err := store.GetLink(context.Background(), "missing1"); err != storage.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound from GetLink, got %v", err)
+	}
+}
+
+func TestSQLiteStorage_Expiry(t *testing.T) {
+	store := openSQLiteStorage(t, ":memory:")
+	defer store.Close()
+
+	past := time.Now().Add(-time.Minute)
+	store.Save(context.Background(), storage.Link{ShortCode: "expired1", LongURL: "https://www.example.com/old", ExpiresAt: past})
+	store.Save(context.Background(), storage.Link{ShortCode: "live0001", LongURL: "https://www.example.com/live"})
+
+	if _, err := store.GetLongURL(context.Background(), "expired1"); err != storage.ErrExpired {
+		t.Fatalf("Expected ErrExpired, got %v", err)
+	}
+	if store.Exists(context.Background(), "expired1") {
+		t.Fatalf("Expected expired code not to exist")
+	}
+	if _, err := store.GetShortCode(context.Background(), "https://www.example.com/old"); err != storage.ErrNotFound {
+		t.Fatalf("Expected expired link to be ignored by reverse lookup, got %v", err)
+	}
+
+	if err := store.Save(context.Background(), storage.Link{ShortCode: "expired1", LongURL: "https://www.example.com/new"}); err != nil {
+		t.Fatalf("Expected expired code to be reusable, got %v", err)
+	}
+	store.Save(context.Background(), storage.Link{ShortCode: "expired2", LongURL: "https://www.example.com/gone", ExpiresAt: past})
+
+	if purged := store.PurgeExpired(); purged != 1 {
+		t.Fatalf("Expected 1 purged link, got %d", purged)
+	}
+	if _, err := store.GetLink(context.Background(), "expired2"); err != storage.ErrNotFound {
+		t.Fatalf("Expected purged link to be gone, got %v", err)
+	}
+	if !store.Exists(context.Background(), "live0001") || !store.Exists(context.Background(), "expired1") {
+		t.Fatalf("Expected live links to survive the purge")
+	}
+}
+
+func TestSQLiteStorage_List(t *testing.T) {
+	sqliteStore := openSQLiteStorage(t, ":memory:")
+	defer sqliteStore.Close()
+	memStore := storage.NewMemoryStorage(nil)
+
+	base := time.Now()
+	for i, host := range []string{"a.example.com", "b.example.com", "a.example.com", "c.example.com", "a.example.com"} {
+		link := storage.Link{
+			ShortCode: string(rune('e'-i)) + "code",
+			LongURL:   "https://" + host + "/" + string(rune('a'+i)),
+			CreatedAt: base.Add(time.Duration(i%3) * time.Second),
+		}
+		sqliteStore.Save(context.Background(), link)
+		memStore.Save(context.Background(), link)
+	}
+
+	for _, opts := range []storage.ListOptions{{Limit: 2}, {Limit: 1, Host: "A.example.com"}, {Limit: 10, Query: "CODE"}} {
+		got := collectPages(t, sqliteStore, opts)
+		want := collectPages(t, memStore, opts)
+		if len(got) != len(want) {
+			t.Fatalf("Expected %d links for %+v, got %d", len(want), opts, len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("Expected listing order %v, got %v", want, got)
+			}
+		}
+	}
+
+	if _, err := sqliteStore.List(context.Background(), storage.ListOptions{Cursor: "%%%"}); err != storage.ErrInvalidCursor {
+		t.Fatalf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestSQLiteStorage_Service(t *testing.T) {
+	store := openSQLiteStorage(t, ":memory:")
+	defer store.Close()
+	svc := service.NewURLService(store, "http://localhost:8080", service.CodeGeneratorFunc(collidingGenerator), nil)
+
+	_, first, err := svc.ShortenURL(context.Background(), "https://www.example.com/first")
+	if err != nil {
+		t.Fatalf("First shorten failed: %v", err)
+	}
+	_, second, err := svc.ShortenURL(context.Background(), "https://www.example.com/second")
+	if err != nil {
+		t.Fatalf("Second shorten failed: %v", err)
+	}
+	if first == second {
+		t.Fatalf("Expected collision to be resolved, both got %s", first)
+	}
+
+	_, again, _ := svc.ShortenURL(context.Background(), "https://www.example.com/second")
+	if again != second {
+		t.Fatalf("Expected idempotent shortening, got %s and %s", second, again)
+	}
+
+	longURL, err := svc.GetLongURL(context.Background(), first)
+	if err != nil || longURL != "https://www.example.com/first" {
+		t.Fatalf("Expected first link to keep its destination, got %s, %v", longURL, err)
+	}
+	if _, err := svc.GetLongURL(context.Background(), "unknown1"); err != storage.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+This file contains unit tests for multi-tenant namespaces.
+
+- TestLoadTenantFile: a tenants file is loaded with each tenant's hosts, and bad ids, base URLs and hosts claimed twice are rejected.
+- TestAddTenant_Conflicts: a tenant id or host can only be added once.
+- TestTenant_RedirectByHost: the same code redirects to each tenant's own URL by Host header, unknown hosts fall back to the default tenant.
+- TestTenant_ShortenByKey: /api/shorten stores into the key's tenant under its base URL, idempotently within the tenant and separately from other tenants.
+- TestTenant_UnknownKeyTenant: a key naming a tenant that is not configured gets 403.
+- TestTenant_Stats: clicks are counted per tenant, so equal codes in two tenants have separate stats.
+*/
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"URL_Shortener_Ruckus_Networks/internals/analytics"
+	"URL_Shortener_Ruckus_Networks/internals/auth"
+	"URL_Shortener_Ruckus_Networks/internals/handler"
+	"URL_Shortener_Ruckus_Networks/internals/service"
+	"URL_Shortener_Ruckus_Networks/internals/storage"
+	"URL_Shortener_Ruckus_Networks/internals/tenant"
+
+	"github.com/gorilla/mux"
+)
+
+// tenantSetup is a handler serving the default tenant and "acme" on acme.link
+type tenantSetup struct {
+	router   *mux.Router
+	handler  *handler.Handler
+	services map[string]*service.URLService // by tenant id, "" for the default
+	keys     *auth.MemoryKeyStore
+	clicks   *analytics.Recorder
+}
+
+func setupTenants(t *testing.T) *tenantSetup {
+	s := &tenantSetup{
+		services: map[string]*service.URLService{
+			"":     service.NewURLService(storage.NewMemoryStorage(nil), "http://localhost:8080", nil, nil),
+			"acme": service.NewURLService(storage.NewMemoryStorage(nil), "https://acme.link", nil, nil),
+		},
+		clicks: analytics.NewRecorder(analytics.NewAggregator(), 100, nil),
+	}
+	s.handler = handler.NewHandler(s.services[""], s.clicks, nil)
+	if err := s.handler.AddTenant("acme", []string{"acme.link", "go.acme.com"}, s.services["acme"]); err != nil {
+		t.Fatalf("AddTenant failed: %v", err)
+	}
+	s.keys, _ = auth.NewMemoryKeyStore()
+	s.handler.SetKeyStore(s.keys)
+
+	s.router = mux.NewRouter()
+	api := s.router.PathPrefix("/api").Subrouter()
+	api.Use(s.handler.Authenticate)
+	api.HandleFunc("/shorten", s.handler.Require(auth.ScopeCreate, s.handler.ShortenURL)).Methods("POST")
+	api.HandleFunc("/links", s.handler.Require(auth.ScopeRead, s.handler.ListLinks)).Methods("GET")
+	api.HandleFunc("/links/{shortCode}/stats", s.handler.Require(auth.ScopeRead, s.handler.LinkStats)).Methods("GET")
+	s.router.HandleFunc("/{shortCode}", s.handler.RedirectURL).Methods("GET", "HEAD")
+	return s
+}
+
+// key adds an admin key of tenant and returns the raw key
+func (s *tenantSetup) key(t *testing.T, tenantID string) string {
+	raw, hash, _ := auth.GenerateKey()
+	if err := s.keys.Add(auth.Key{ID: "key-" + tenantID + "-" + hash[:8], Hash: hash, Scopes: []auth.Scope{auth.ScopeAdmin}, Tenant: tenantID}); err != nil {
+		t.Fatalf("Add key failed: %v", err)
+	}
+	return raw
+}
+
+func redirectOnHost(router *mux.Router, host, code string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/"+code, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoadTenantFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	os.WriteFile(path, []byte(`[
+		{"id":"acme","base_url":"https://acme.link","hosts":["go.acme.com"]},
+		{"id":"globex","base_url":"https://GLBX.io:8443"}
+	]`), 0600)
+
+	tenants, err := tenant.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(tenants) != 2 || !slices.Equal(tenants[0].AllHosts(), []string{"acme.link", "go.acme.com"}) ||
+		!slices.Equal(tenants[1].AllHosts(), []string{"glbx.io"}) {
+		t.Fatalf("Unexpected tenants %+v", tenants)
+	}
+
+	for name, content := range map[string]string{
+		"bad id":         `[{"id":"Acme Inc","base_url":"https://acme.link"}]`,
+		"relative url":   `[{"id":"acme","base_url":"acme.link"}]`,
+		"trailing slash": `[{"id":"acme","base_url":"https://acme.link/"}]`,
+		"duplicate id":   `[{"id":"acme","base_url":"https://acme.link"},{"id":"acme","base_url":"https://acme2.link"}]`,
+		"duplicate host": `[{"id":"acme","base_url":"https://acme.link"},{"id":"globex","base_url":"https://glbx.io","hosts":["ACME.link"]}]`,
+		"not an array":   `{"id":"acme"}`,
+	} {
+		os.WriteFile(path, []byte(content), 0600)
+		if _, err := tenant.LoadFile(path); !errors.Is(err, tenant.ErrInvalidTenant) {
+			t.Errorf("%s: expected ErrInvalidTenant, got %v", name, err)
+		}
+	}
+}
+
+func TestAddTenant_Conflicts(t *testing.T) {
+	s := setupTenants(t)
+	svc := service.NewURLService(storage.NewMemoryStorage(nil), "https://other.link", nil, nil)
+
+	if err := s.handler.AddTenant("acme", []string{"other.link"}, svc); !errors.Is(err, tenant.ErrInvalidTenant) {
+		t.Fatalf("Expected duplicate id to be rejected, got %v", err)
+	}
+	if err := s.handler.AddTenant("other", []string{"other.link", "Go.Acme.com:443"}, svc); !errors.Is(err, tenant.ErrInvalidTenant) {
+		t.Fatalf("Expected host of another tenant to be rejected, got %v", err)
+	}
+	if err := s.handler.AddTenant("other", []string{"other.link"}, svc); err != nil {
+		t.Fatalf("Expected a new tenant to be added, got %v", err)
+	}
+}
+
+func TestTenant_RedirectByHost(t *testing.T) {
+	s := setupTenants(t)
+	ctx := context.Background()
+	s.services[""].ShortenURLWithAlias(ctx, "https://www.example.com/default-sale", "sale")
+	s.services["acme"].ShortenURLWithAlias(ctx, "https://www.acme.com/sale", "sale")
+
+	for host, want := range map[string]string{
+		"acme.link":        "https://www.acme.com/sale",
+		"ACME.link:443":    "https://www.acme.com/sale",
+		"go.acme.com":      "https://www.acme.com/sale",
+		"localhost:8080":   "https://www.example.com/default-sale",
+		"unknown.host.com": "https://www.example.com/default-sale",
+	} {
+		w := redirectOnHost(s.router, host, "sale")
+		if w.Code != http.StatusFound || w.Header().Get("Location") != want {
+			t.Errorf("%s: expected redirect to %s, got %d %s", host, want, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	s.services["acme"].ShortenURLWithAlias(ctx, "https://www.acme.com/only", "acme-only")
+	if w := redirectOnHost(s.router, "localhost:8080", "acme-only"); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected acme's code to be unknown to the default tenant, got %d", w.Code)
+	}
+}
+
+func TestTenant_ShortenByKey(t *testing.T) {
+	s := setupTenants(t)
+	acmeKey, defaultKey := s.key(t, "acme"), s.key(t, "")
+	body := `{"url":"https://www.example.com/shared"}`
+
+	shorten := func(raw string) string {
+		w := bearer(s.router, "POST", "/api/shorten", body, raw)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp handler.ShortenResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.ShortURL
+	}
+
+	first, again := shorten(acmeKey), shorten(acmeKey)
+	if !strings.HasPrefix(first, "https://acme.link/") || first != again {
+		t.Fatalf("Expected one acme.link short URL, got %s and %s", first, again)
+	}
+	if other := shorten(defaultKey); !strings.HasPrefix(other, "http://localhost:8080/") {
+		t.Fatalf("Expected the default tenant's base URL, got %s", other)
+	}
+
+	for raw, want := range map[string]int{acmeKey: 1, defaultKey: 1} {
+		var resp handler.ListLinksResponse
+		json.NewDecoder(bearer(s.router, "GET", "/api/links", "", raw).Body).Decode(&resp)
+		if len(resp.Links) != want {
+			t.Fatalf("Expected %d link per tenant, got %d", want, len(resp.Links))
+		}
+	}
+}
+
+func TestTenant_UnknownKeyTenant(t *testing.T) {
+	s := setupTenants(t)
+	raw := s.key(t, "initech")
+
+	w := bearer(s.router, "POST", "/api/shorten", `{"url":"https://www.example.com/x"}`, raw)
+	if w.Code != http.StatusForbidden || errorCode(t, w) != handler.CodeForbidden {
+		t.Fatalf("Expected 403 forbidden for a key of an unknown tenant, got %d", w.Code)
+	}
+}
+
+func TestTenant_Stats(t *testing.T) {
+	s := setupTenants(t)
+	ctx := context.Background()
+	s.services[""].ShortenURLWithAlias(ctx, "https://www.example.com/default-sale", "sale")
+	s.services["acme"].ShortenURLWithAlias(ctx, "https://www.acme.com/sale", "sale")
+
+	redirectOnHost(s.router, "acme.link", "sale")
+	redirectOnHost(s.router, "acme.link", "sale")
+	redirectOnHost(s.router, "localhost:8080", "sale")
+	s.clicks.Close()
+
+	for raw, want := range map[string]int64{s.key(t, "acme"): 2, s.key(t, ""): 1} {
+		var resp handler.StatsResponse
+		json.NewDecoder(bearer(s.router, "GET", "/api/links/sale/stats", "", raw).Body).Decode(&resp)
+		if resp.Total != want {
+			t.Fatalf("Expected %d clicks, got %d", want, resp.Total)
+		}
+	}
+}